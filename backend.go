@@ -0,0 +1,63 @@
+package gent
+
+import "fmt"
+
+// Format is an output format the generator can render node types to.
+type Format string
+
+const (
+	// Go types wrapping Tree-sitter nodes. This is the default.
+	FormatGo Format = "go"
+	// A JSON Schema describing the JSON output of the generated Go types.
+	FormatJSONSchema Format = "jsonschema"
+	// Protocol Buffers messages with the same shape as the JSON output.
+	FormatProtobuf Format = "proto"
+	// TypeScript type definitions for the JSON output.
+	FormatTypeScript Format = "typescript"
+	// Markdown reference documentation for the generated Go API.
+	FormatMarkdown Format = "markdown"
+	// HTML reference documentation for the generated Go API.
+	FormatHTML Format = "html"
+	// A Graphviz DOT graph of the supertypes, fields and children of each kind.
+	FormatDOT Format = "dot"
+	// A Mermaid graph of the supertypes, fields and children of each kind.
+	FormatMermaid Format = "mermaid"
+)
+
+// Formats contains every supported output format.
+var Formats = []Format{FormatGo, FormatJSONSchema, FormatProtobuf, FormatTypeScript, FormatMarkdown, FormatHTML, FormatDOT, FormatMermaid}
+
+// Names of the range and point types in the non-Go backends. These contain an
+// underscore so that they can never clash with a struct name created from a node kind.
+const (
+	rangeTypeName = "Gent_Range"
+	pointTypeName = "Gent_Point"
+)
+
+// A backend renders the node type model built from node-types.json into a specific
+// output format.
+type backend interface {
+	render(nodeTypes nodeTypes, nm *nodeMap) (string, error)
+}
+
+func (g *Generator) backend() (backend, error) {
+	switch g.options.Format {
+	case "", FormatGo:
+		return &goBackend{options: g.options}, nil
+	case FormatJSONSchema:
+		return &jsonSchemaBackend{}, nil
+	case FormatProtobuf:
+		return &protobufBackend{options: g.options}, nil
+	case FormatTypeScript:
+		return &typeScriptBackend{}, nil
+	case FormatMarkdown:
+		return &docsBackend{options: g.options}, nil
+	case FormatHTML:
+		return &docsBackend{options: g.options, html: true}, nil
+	case FormatDOT:
+		return &graphBackend{options: g.options}, nil
+	case FormatMermaid:
+		return &graphBackend{options: g.options, mermaid: true}, nil
+	}
+	return nil, fmt.Errorf("Unknown output format %s, expected one of %v", g.options.Format, Formats)
+}
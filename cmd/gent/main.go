@@ -3,9 +3,12 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
 
+	"github.com/dave/jennifer/jen"
 	"github.com/isaacharrisholt/gent"
 	"github.com/urfave/cli/v3"
 )
@@ -16,6 +19,12 @@ func main() {
 		Usage: "Go ENhancements for Tree-sitter",
 		Commands: []*cli.Command{
 			generateCommand(),
+			schemaCommand(),
+			docsCommand(),
+			graphCommand(),
+			corpusCommand(),
+			coverageCommand(),
+			diffCommand(),
 		},
 	}
 
@@ -27,8 +36,8 @@ func main() {
 func generateCommand() *cli.Command {
 	return &cli.Command{
 		Name:                   "generate",
-		Usage:                  "Generate Go types from Tree-sitter node-types.json files",
-		UsageText:              "gent generate [OPTIONS] <PATH TO NODE-TYPES.JSON>",
+		Usage:                  "Generate Go types, or other type definitions, from Tree-sitter node-types.json files",
+		UsageText:              "gent generate [OPTIONS] <PATH TO NODE-TYPES.JSON>\n   gent generate [OPTIONS] --module <MODULE>",
 		Aliases:                []string{"gen"},
 		Action:                 generateCommandAction,
 		EnableShellCompletion:  true,
@@ -46,6 +55,37 @@ func generateCommand() *cli.Command {
 				Aliases: []string{"o"},
 				Usage:   "Specify the `OUTPUT` file path used for the generated code. If not specified, the output will be written to stdout.",
 			},
+			&cli.StringFlag{
+				Name:    "module",
+				Aliases: []string{"m"},
+				Usage:   "Read node-types.json from the Go `MODULE` of a grammar, e.g. github.com/tree-sitter/tree-sitter-python, or a package in it, instead of a path. The module is resolved by the go command using the version in go.mod, or the one given with `@VERSION`, and read from the module cache.",
+			},
+			&cli.StringFlag{
+				Name:    "language",
+				Aliases: []string{"l"},
+				Usage:   "Bind the generated code to the Go bindings of the grammar at the import `PATH`, e.g. github.com/tree-sitter/tree-sitter-python/bindings/go, adding Parse functions.",
+			},
+			&cli.StringFlag{
+				Name:    "format",
+				Aliases: []string{"f"},
+				Usage:   fmt.Sprintf("Specify the `FORMAT` of the generated code. One of %v.", gent.Formats),
+				Value:   string(gent.FormatGo),
+			},
+			&cli.StringFlag{
+				Name:    "config",
+				Aliases: []string{"c"},
+				Usage:   "Specify the `CONFIG` file path used to customise the generated code.",
+			},
+			&cli.BoolFlag{
+				Name:  "performance",
+				Usage: "Generate allocation-free Append methods, which iterate with pooled cursors and write into caller-supplied buffers.",
+				Value: false,
+			},
+			&cli.BoolFlag{
+				Name:  "compact",
+				Usage: "Generate compact code, where accessors delegate to generic runtime helpers. This shrinks the output for large grammars.",
+				Value: false,
+			},
 			&cli.BoolFlag{
 				Name:  "debug",
 				Usage: "Run the generator in debug mode, adding extra comments to the generated file and printing debug logs to stderr.",
@@ -56,23 +96,169 @@ func generateCommand() *cli.Command {
 }
 
 func generateCommandAction(ctx context.Context, cmd *cli.Command) error {
-	if len(cmd.Args().Slice()) == 0 {
+	if len(cmd.Args().Slice()) == 0 && cmd.String("module") == "" {
 		return cli.ShowSubcommandHelp(cmd)
 	}
 
 	filePath := cmd.Args().First()
+	if cmd.String("module") != "" {
+		if filePath != "" {
+			return fmt.Errorf("Cannot use --module with a node-types.json path")
+		}
+		var err error
+		filePath, err = gent.ResolveModuleNodeTypes(cmd.String("module"))
+		if err != nil {
+			return fmt.Errorf("Failed to resolve module: %w", err)
+		}
+	}
 	fileContent, err := os.ReadFile(filePath)
 	if err != nil {
 		return fmt.Errorf("Failed to read from %s: %w", filePath, err)
 	}
 
+	config, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+
 	generator := gent.NewGenerator(gent.GeneratorOptions{
 		PackageName: cmd.String("package"),
 		Debug:       cmd.Bool("debug"),
+		Format:      gent.Format(cmd.String("format")),
+		Config:      config,
+		Language:    cmd.String("language"),
+		Performance: cmd.Bool("performance"),
+		Compact:     cmd.Bool("compact"),
+	})
+	output, err := generator.Generate(fileContent)
+	if err != nil {
+		return fmt.Errorf("Failed to generate code: %w", err)
+	}
+
+	if cmd.String("output") != "" {
+		if err := os.WriteFile(cmd.String("output"), []byte(output), 0644); err != nil {
+			return fmt.Errorf("Failed to write to %s: %w", cmd.String("output"), err)
+		}
+		return nil
+	}
+
+	fmt.Print(output)
+
+	return nil
+}
+
+func schemaCommand() *cli.Command {
+	return &cli.Command{
+		Name:                   "schema",
+		Usage:                  "Generate a JSON Schema for the JSON output of the generated types",
+		UsageText:              "gent schema [OPTIONS] <PATH TO NODE-TYPES.JSON>",
+		Action:                 schemaCommandAction,
+		EnableShellCompletion:  true,
+		Suggest:                true,
+		UseShortOptionHandling: true,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Usage:   "Specify the `OUTPUT` file path used for the schema. If not specified, the output will be written to stdout.",
+			},
+		},
+	}
+}
+
+func schemaCommandAction(ctx context.Context, cmd *cli.Command) error {
+	if len(cmd.Args().Slice()) == 0 {
+		return cli.ShowSubcommandHelp(cmd)
+	}
+
+	filePath := cmd.Args().First()
+	fileContent, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("Failed to read from %s: %w", filePath, err)
+	}
+
+	generator := gent.NewGenerator(gent.GeneratorOptions{})
+	output, err := generator.GenerateJSONSchema(fileContent)
+	if err != nil {
+		return fmt.Errorf("Failed to generate JSON schema: %w", err)
+	}
+
+	if cmd.String("output") != "" {
+		if err := os.WriteFile(cmd.String("output"), []byte(output), 0644); err != nil {
+			return fmt.Errorf("Failed to write to %s: %w", cmd.String("output"), err)
+		}
+		return nil
+	}
+
+	fmt.Print(output)
+
+	return nil
+}
+
+func docsCommand() *cli.Command {
+	return &cli.Command{
+		Name:                   "docs",
+		Usage:                  "Generate reference documentation for the generated Go types",
+		UsageText:              "gent docs [OPTIONS] <PATH TO NODE-TYPES.JSON>",
+		Action:                 docsCommandAction,
+		EnableShellCompletion:  true,
+		Suggest:                true,
+		UseShortOptionHandling: true,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "package",
+				Aliases: []string{"p"},
+				Usage:   "Specify the `PACKAGE` name of the generated code being documented.",
+				Value:   "node_types",
+			},
+			&cli.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Usage:   "Specify the `OUTPUT` file path used for the documentation. If not specified, the output will be written to stdout.",
+			},
+			&cli.StringFlag{
+				Name:    "config",
+				Aliases: []string{"c"},
+				Usage:   "Specify the `CONFIG` file path used to customise the generated code.",
+			},
+			&cli.BoolFlag{
+				Name:  "html",
+				Usage: "Generate a standalone HTML page instead of Markdown.",
+				Value: false,
+			},
+		},
+	}
+}
+
+func docsCommandAction(ctx context.Context, cmd *cli.Command) error {
+	if len(cmd.Args().Slice()) == 0 {
+		return cli.ShowSubcommandHelp(cmd)
+	}
+
+	filePath := cmd.Args().First()
+	fileContent, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("Failed to read from %s: %w", filePath, err)
+	}
+
+	format := gent.FormatMarkdown
+	if cmd.Bool("html") {
+		format = gent.FormatHTML
+	}
+
+	config, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	generator := gent.NewGenerator(gent.GeneratorOptions{
+		PackageName: cmd.String("package"),
+		Format:      format,
+		Config:      config,
 	})
 	output, err := generator.Generate(fileContent)
 	if err != nil {
-		return fmt.Errorf("Failed to generate Go code: %w", err)
+		return fmt.Errorf("Failed to generate documentation: %w", err)
 	}
 
 	if cmd.String("output") != "" {
@@ -86,3 +272,198 @@ func generateCommandAction(ctx context.Context, cmd *cli.Command) error {
 
 	return nil
 }
+
+func graphCommand() *cli.Command {
+	return &cli.Command{
+		Name:                   "graph",
+		Usage:                  "Export the node type graph as Graphviz DOT or Mermaid",
+		UsageText:              "gent graph [OPTIONS] <PATH TO NODE-TYPES.JSON>",
+		Action:                 graphCommandAction,
+		EnableShellCompletion:  true,
+		Suggest:                true,
+		UseShortOptionHandling: true,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Usage:   "Specify the `OUTPUT` file path used for the graph. If not specified, the output will be written to stdout.",
+			},
+			&cli.BoolFlag{
+				Name:  "mermaid",
+				Usage: "Output a Mermaid graph instead of Graphviz DOT.",
+				Value: false,
+			},
+			&cli.StringFlag{
+				Name:    "root",
+				Aliases: []string{"r"},
+				Usage:   "Only include kinds reachable from the Tree-sitter `KIND`.",
+			},
+			&cli.IntFlag{
+				Name:    "depth",
+				Aliases: []string{"d"},
+				Usage:   "The maximum number of edges to follow from the root kind. If 0, there's no limit.",
+				Value:   0,
+			},
+			&cli.IntFlag{
+				Name:  "large-union",
+				Usage: "Highlight unions with at least `SIZE` members.",
+				Value: 10,
+			},
+		},
+	}
+}
+
+func graphCommandAction(ctx context.Context, cmd *cli.Command) error {
+	if len(cmd.Args().Slice()) == 0 {
+		return cli.ShowSubcommandHelp(cmd)
+	}
+
+	filePath := cmd.Args().First()
+	fileContent, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("Failed to read from %s: %w", filePath, err)
+	}
+
+	format := gent.FormatDOT
+	if cmd.Bool("mermaid") {
+		format = gent.FormatMermaid
+	}
+
+	generator := gent.NewGenerator(gent.GeneratorOptions{
+		Format: format,
+		Graph: gent.GraphOptions{
+			Root:           cmd.String("root"),
+			Depth:          int(cmd.Int("depth")),
+			LargeUnionSize: int(cmd.Int("large-union")),
+		},
+	})
+	output, err := generator.Generate(fileContent)
+	if err != nil {
+		return fmt.Errorf("Failed to generate graph: %w", err)
+	}
+
+	if cmd.String("output") != "" {
+		if err := os.WriteFile(cmd.String("output"), []byte(output), 0644); err != nil {
+			return fmt.Errorf("Failed to write to %s: %w", cmd.String("output"), err)
+		}
+		return nil
+	}
+
+	fmt.Print(output)
+
+	return nil
+}
+
+func corpusCommand() *cli.Command {
+	return &cli.Command{
+		Name:                   "corpus",
+		Usage:                  "Parse the examples in Tree-sitter corpus files with a generated package, checking its accessors and the expected trees",
+		UsageText:              "gent corpus --language <IMPORT PATH> <CORPUS FILES OR DIRECTORIES>...",
+		Action:                 corpusCommandAction,
+		EnableShellCompletion:  true,
+		Suggest:                true,
+		UseShortOptionHandling: true,
+		Flags:                  []cli.Flag{languageFlag()},
+	}
+}
+
+func corpusCommandAction(ctx context.Context, cmd *cli.Command) error {
+	if len(cmd.Args().Slice()) == 0 {
+		return cli.ShowSubcommandHelp(cmd)
+	}
+
+	paths, err := absolutePaths(cmd.Args().Slice())
+	if err != nil {
+		return err
+	}
+	return runTool(ctx, cmd.String("language"), os.Stdout, "Corpus", paths)
+}
+
+func coverageCommand() *cli.Command {
+	return &cli.Command{
+		Name:                   "coverage",
+		Usage:                  "Parse sources with a generated package, reporting which kinds, fields and union members declared in its node-types.json occur in them",
+		UsageText:              "gent coverage [OPTIONS] --language <IMPORT PATH> <SOURCE FILES OR DIRECTORIES>...",
+		Action:                 coverageCommandAction,
+		EnableShellCompletion:  true,
+		Suggest:                true,
+		UseShortOptionHandling: true,
+		Flags: []cli.Flag{
+			languageFlag(),
+			&cli.StringSliceFlag{
+				Name:    "ext",
+				Aliases: []string{"e"},
+				Usage:   "Only parse files in directories with the `EXTENSION`, e.g. .py. Can be given multiple times. If not specified, every file is parsed.",
+			},
+			&cli.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Usage:   "Specify the `OUTPUT` file path used for the report. If not specified, the output will be written to stdout.",
+			},
+		},
+	}
+}
+
+func coverageCommandAction(ctx context.Context, cmd *cli.Command) error {
+	if len(cmd.Args().Slice()) == 0 {
+		return cli.ShowSubcommandHelp(cmd)
+	}
+
+	roots, err := absolutePaths(cmd.Args().Slice())
+	if err != nil {
+		return err
+	}
+	extensions := []jen.Code{}
+	for _, extension := range cmd.StringSlice("ext") {
+		extensions = append(extensions, jen.Lit(extension))
+	}
+
+	var output io.Writer = os.Stdout
+	if cmd.String("output") != "" {
+		file, err := os.Create(cmd.String("output"))
+		if err != nil {
+			return fmt.Errorf("Failed to write to %s: %w", cmd.String("output"), err)
+		}
+		defer file.Close()
+		output = file
+	}
+
+	return runTool(ctx, cmd.String("language"), output, "Coverage", roots, jen.Index().String().Values(extensions...))
+}
+
+func diffCommand() *cli.Command {
+	return &cli.Command{
+		Name:                   "diff",
+		Usage:                  "Parse two source files with a generated package, showing the structural differences between them with children aligned by the fields declared in node-types.json",
+		UsageText:              "gent diff --language <IMPORT PATH> <OLD FILE> <NEW FILE>",
+		Action:                 diffCommandAction,
+		EnableShellCompletion:  true,
+		Suggest:                true,
+		UseShortOptionHandling: true,
+		Flags:                  []cli.Flag{languageFlag()},
+	}
+}
+
+func diffCommandAction(ctx context.Context, cmd *cli.Command) error {
+	if len(cmd.Args().Slice()) != 2 {
+		return cli.ShowSubcommandHelp(cmd)
+	}
+
+	paths := []jen.Code{}
+	for _, path := range cmd.Args().Slice() {
+		absolute, err := filepath.Abs(path)
+		if err != nil {
+			return fmt.Errorf("Failed to resolve %s: %w", path, err)
+		}
+		paths = append(paths, jen.Lit(absolute))
+	}
+	return runTool(ctx, cmd.String("language"), os.Stdout, "Diff", paths...)
+}
+
+// loadConfig loads the config file given by the `config` flag, if any.
+func loadConfig(cmd *cli.Command) (gent.Config, error) {
+	if cmd.String("config") == "" {
+		return gent.Config{}, nil
+	}
+	return gent.LoadConfig(cmd.String("config"))
+}
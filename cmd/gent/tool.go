@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/dave/jennifer/jen"
+	"github.com/urfave/cli/v3"
+)
+
+const toolPackage = "github.com/isaacharrisholt/gent/tool"
+
+// languageFlag is the flag of the commands that parse sources, giving the generated
+// package to parse them with.
+func languageFlag() *cli.StringFlag {
+	return &cli.StringFlag{
+		Name:     "language",
+		Aliases:  []string{"l", "lang"},
+		Usage:    "Specify the import `PATH` of a package generated with `gent generate --language`, which is used to parse sources. It's resolved by the go.mod in the working directory.",
+		Required: true,
+	}
+}
+
+// runTool calls a function of the tool package with the generated package at the
+// given import path linked in, writing its output to the given writer.
+//
+// gent doesn't contain any grammars, so it writes a program that imports the package
+// and builds it in the working directory. The package is resolved through
+// the go.mod there, as with any other import, and registers its binding with the
+// runtime package when it's initialised, which the tool package uses.
+func runTool(ctx context.Context, importPath string, stdout io.Writer, function string, args ...jen.Code) error {
+	file := jen.NewFile("main")
+	file.Anon(importPath)
+	file.Func().Id("main").Params().Block(
+		jen.If(
+			jen.Err().Op(":=").Qual(toolPackage, function).Call(append([]jen.Code{jen.Qual("os", "Stdout")}, args...)...),
+			jen.Err().Op("!=").Nil(),
+		).Block(
+			jen.Qual("fmt", "Fprintln").Call(jen.Qual("os", "Stderr"), jen.Err()),
+			jen.Qual("os", "Exit").Call(jen.Lit(1)),
+		),
+	)
+
+	dir, err := os.MkdirTemp("", "gent-")
+	if err != nil {
+		return fmt.Errorf("Failed to create a directory for the %s program: %w", function, err)
+	}
+	defer os.RemoveAll(dir)
+	programPath := filepath.Join(dir, "main.go")
+	if err := file.Save(programPath); err != nil {
+		return fmt.Errorf("Failed to write the %s program: %w", function, err)
+	}
+
+	binaryPath := filepath.Join(dir, "gent-"+strings.ToLower(function))
+	build := exec.CommandContext(ctx, "go", "build", "-o", binaryPath, programPath)
+	build.Stderr = os.Stderr
+	if err := build.Run(); err != nil {
+		return fmt.Errorf("Failed to build the %s program with %s: %w", function, importPath, err)
+	}
+
+	cmd := exec.CommandContext(ctx, binaryPath)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		// The program has already reported why it failed
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return cli.Exit("", exitErr.ExitCode())
+		}
+		return fmt.Errorf("Failed to run the %s program: %w", function, err)
+	}
+	return nil
+}
+
+// absolutePaths returns a literal list of the given paths made absolute, to pass to a
+// tool function.
+func absolutePaths(paths []string) (jen.Code, error) {
+	values := []jen.Code{}
+	for _, path := range paths {
+		absolute, err := filepath.Abs(path)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to resolve %s: %w", path, err)
+		}
+		values = append(values, jen.Lit(absolute))
+	}
+	return jen.Index().String().Values(values...), nil
+}
@@ -0,0 +1,126 @@
+package gent
+
+import (
+	"github.com/dave/jennifer/jen"
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+// kindSetVarName returns the name of the package-level variable holding the kinds a
+// struct can wrap, used in compact mode.
+func kindSetVarName(structName string) string {
+	return "kinds_" + structName
+}
+
+// writeKindSets adds a precomputed `runtime.KindSet` for each struct that union
+// accessors and constructors check kinds against in compact mode.
+func writeKindSets(file *jen.File, nodeTypes nodeTypes, nm *nodeMap) {
+	kindSets := orderedmap.New[string, []string]()
+	allKinds := []string{}
+	for _, nodeType := range nodeTypes {
+		if nodeType.Subtypes != nil {
+			continue
+		}
+		structName, _ := nm.getStructName(nodeType.Type, nodeType.Named)
+		kindSets.Set(structName, []string{nodeType.Type})
+		allKinds = append(allKinds, nodeType.Type)
+	}
+	for tsKind, structName := range nm.unknown.FromOldest() {
+		kindSets.Set(structName, []string{tsKind})
+	}
+	for _, supertype := range nm.supertypes.FromOldest() {
+		kindSets.Set(supertype.name, nm.getTSRecursiveTSKindsOf(supertype.members))
+	}
+	for _, unionType := range nm.unionTypes.FromOldest() {
+		kindSets.Set(unionType.name, nm.getTSRecursiveTSKindsOf(unionType.members))
+	}
+	kindSets.Set(nm.names.getStruct(anyNodeStructName), allKinds)
+
+	defs := []jen.Code{}
+	unionDefs := []jen.Code{}
+	for structName, tsKinds := range kindSets.FromOldest() {
+		kinds := []jen.Code{}
+		for _, tsKind := range tsKinds {
+			kinds = append(kinds, jen.Lit(tsKind))
+		}
+		def := jen.Id(kindSetVarName(structName)).Op("=").Qual(runtimePackage, "NewKindSet").Call(kinds...)
+		if len(tsKinds) == 1 {
+			defs = append(defs, def)
+		} else {
+			unionDefs = append(unionDefs, def)
+		}
+	}
+
+	file.Comment("Kinds each struct can wrap, precomputed so that kind checks don't allocate.")
+	file.Var().Defs(defs...)
+	// Union names can be very long, so their sets are declared separately rather than
+	// padding every line of the block to the longest name
+	for _, def := range unionDefs {
+		file.Var().Add(def)
+	}
+}
+
+// compactAccessorCall returns the call to the runtime helper that implements a field
+// or union member accessor in compact mode.
+func compactAccessorCall(stDef structDef, fieldDef methodDef, structMethodIdentifier string) jen.Code {
+	receiverNode := jen.Op("&").Id(structMethodIdentifier).Dot("Node")
+	switch {
+	case stDef.isUnionType:
+		return jen.Qual(runtimePackage, "As").
+			Types(jen.Id(fieldDef.returnType)).
+			Call(receiverNode, jen.Id(kindSetVarName(fieldDef.returnType)))
+	case fieldDef.array:
+		return jen.Qual(runtimePackage, "Fields").
+			Types(jen.Id(fieldDef.returnType)).
+			Call(receiverNode, jen.Id(fieldIDVarName(fieldDef.tsFieldName)), jen.Id("cursor"))
+	default:
+		return jen.Qual(runtimePackage, "Field").
+			Types(jen.Id(fieldDef.returnType)).
+			Call(receiverNode, jen.Id(fieldIDVarName(fieldDef.tsFieldName)))
+	}
+}
+
+// writeCompactFunctions adds the package-level functions that replace the
+// `ToJSONNode`, `LeadingExtras`, `TrailingExtras` and `AllChildren` methods in compact
+// mode, so that they're generated once rather than for every struct.
+func writeCompactFunctions(file *jen.File, nm *nodeMap) {
+	typedNodeName := nm.names.get("TypedNode")
+	asNode := jen.Id("node").Dot("AsNode").Call()
+
+	toJSONNodeName := nm.names.get("ToJSONNode")
+	writeDocComment(
+		file,
+		toJSONNodeName+" converts the node into its JSON representation, finding its fields from the grammar. Use `json.Marshal("+toJSONNodeName+"(node, nil))` to marshal a node.",
+	)
+	file.Func().Id(toJSONNodeName).
+		Params(jen.Id("node").Id(typedNodeName), jen.Id("source").Index().Byte()).
+		Op("*").Qual(runtimePackage, "JSONNode").
+		Block(jen.Return(jen.Qual(runtimePackage, "ToJSONNode").Call(jen.Id(nm.names.get("Grammar")), asNode.Clone(), jen.Id("source"))))
+
+	if extrasStructName, ok := nm.getExtrasStructName(); ok {
+		for _, methodName := range []string{"LeadingExtras", "TrailingExtras"} {
+			functionName := nm.names.get(methodName)
+			if methodName == "LeadingExtras" {
+				file.Comment(functionName + " returns the extra nodes, such as comments, directly preceding the node.")
+				file.Comment("Extras on the line the previous node ends on are its trailing extras instead.")
+			} else {
+				file.Comment(functionName + " returns the extra nodes, such as comments, directly following the")
+				file.Comment("node on the line it ends on, or all of them if it's the last node.")
+			}
+			file.Func().Id(functionName).
+				Params(jen.Id("node").Id(typedNodeName)).
+				Index().Op("*").Id(extrasStructName).
+				Block(jen.Return(jen.Qual(runtimePackage, methodName+"Of").Types(jen.Id(extrasStructName)).Call(asNode.Clone())))
+		}
+	}
+
+	allChildrenName := nm.names.get("AllChildren")
+	anyNodeName := nm.names.getStruct(anyNodeStructName)
+	writeDocComment(
+		file,
+		allChildrenName+" returns every child of the node, including unnamed tokens such as operators and keywords, and extras such as comments.",
+	)
+	file.Func().Id(allChildrenName).
+		Params(jen.Id("node").Id(typedNodeName), jen.Id("cursor").Op("*").Qual("github.com/tree-sitter/go-tree-sitter", "TreeCursor")).
+		Index().Id(anyNodeName).
+		Block(jen.Return(jen.Qual(runtimePackage, "AllChildrenOf").Types(jen.Id(anyNodeName)).Call(asNode.Clone(), jen.Id("cursor"))))
+}
@@ -0,0 +1,62 @@
+package gent
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Config is the contents of a gent config file, which customises the generated code
+// beyond what's available in node-types.json.
+type Config struct {
+	// Descriptions added to the doc comments of the generated code. Keys are either a
+	// Tree-sitter kind, e.g. `function_definition`, or a kind and a field name
+	// separated by a dot, e.g. `function_definition.name`.
+	Descriptions map[string]string `json:"descriptions"`
+	// Roles of kinds used to generate the `BuildScopes` function. It's only generated
+	// if at least one kind is a scope.
+	Roles Roles `json:"roles"`
+}
+
+// Roles tags kinds with the roles they play in building a scope tree, like the
+// captures in Tree-sitter's `locals.scm` queries. See `runtime.ScopeRoles`.
+type Roles struct {
+	// Kinds that introduce a scope, e.g. `function_definition`, `class_definition`
+	// and `module`.
+	Scopes []string `json:"scopes"`
+	// Kinds that declare names, mapped to the field holding the name, e.g.
+	// `"function_definition": "name"`. An empty field declares the names in the
+	// node's children outside of fields, e.g. for `parameters`.
+	Definitions map[string]string `json:"definitions"`
+	// Kinds that are names, e.g. `identifier`.
+	References []string `json:"references"`
+	// Kinds that are searched for names in a definition's field, e.g. `pattern_list`.
+	Patterns []string `json:"patterns"`
+	// Kinds mapped to a field that holds neither definitions nor references, e.g.
+	// `"attribute": "attribute"`.
+	Ignored map[string]string `json:"ignored"`
+}
+
+// LoadConfig reads a JSON config file from the given path.
+func LoadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("Failed to read config from %s: %w", path, err)
+	}
+
+	var config Config
+	err = json.Unmarshal(data, &config)
+	if err != nil {
+		return Config{}, fmt.Errorf("Failed to unmarshal config: %w", err)
+	}
+	return config, nil
+}
+
+// description returns the configured description of a kind, or of one of its fields
+// if field is non-empty.
+func (c Config) description(kind string, field string) string {
+	if field != "" {
+		return c.Descriptions[kind+"."+field]
+	}
+	return c.Descriptions[kind]
+}
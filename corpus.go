@@ -0,0 +1,170 @@
+package gent
+
+import (
+	"github.com/dave/jennifer/jen"
+	"github.com/isaacharrisholt/gent/runtime"
+)
+
+// BuildGrammar builds the runtime description of the node types in the given
+// node-types.json data. This is the same value generated packages expose as their
+// `Grammar` variable, so it can be used to check corpus files without generating code.
+func BuildGrammar(data []byte) (*runtime.Grammar, error) {
+	nodeTypes, err := parseNodeTypes(data)
+	if err != nil {
+		return nil, err
+	}
+
+	nm, err := buildNodeMap(nodeTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildGrammar(nodeTypes, &nm), nil
+}
+
+func buildGrammar(nodeTypes nodeTypes, nm *nodeMap) *runtime.Grammar {
+	grammar := &runtime.Grammar{
+		Named:      map[runtime.SyntaxKind]*runtime.KindInfo{},
+		Unnamed:    map[runtime.SyntaxKind]*runtime.KindInfo{},
+		Supertypes: map[runtime.SyntaxKind][]runtime.SyntaxKind{},
+		Extras:     []runtime.SyntaxKind{},
+		Root:       nm.root,
+	}
+
+	for _, extra := range nm.extras {
+		grammar.Extras = append(grammar.Extras, extra.Type)
+	}
+	for tsKind, supertype := range nm.supertypes.FromOldest() {
+		grammar.Supertypes[tsKind] = nm.getTSRecursiveTSKindsOf(supertype.members)
+	}
+
+	for _, nodeType := range nodeTypes {
+		if nodeType.Subtypes != nil {
+			continue
+		}
+
+		structName, _ := nm.getStructName(nodeType.Type, nodeType.Named)
+		info := &runtime.KindInfo{
+			Kind:       nodeType.Type,
+			Named:      nodeType.Named,
+			StructName: structName,
+			Fields:     []runtime.FieldInfo{},
+		}
+		for name, field := range nodeType.Fields.FromOldest() {
+			info.Fields = append(info.Fields, runtime.FieldInfo{
+				Name:     name,
+				Accessor: accessorName(createPrivateName(name)),
+				Multiple: field.Multiple,
+				Required: field.Required,
+				Kinds:    nm.getTSRecursiveTSKindsOf(field.Types),
+			})
+		}
+		if len(nodeType.Children.Types) > 0 {
+			accessor := "TypedChild"
+			if nodeType.Children.Multiple {
+				accessor = "TypedChildren"
+			}
+			info.Children = &runtime.FieldInfo{
+				Accessor: accessor,
+				Multiple: nodeType.Children.Multiple,
+				Required: nodeType.Children.Required,
+				Kinds:    nm.getTSRecursiveTSKindsOf(nodeType.Children.Types),
+			}
+		}
+
+		if nodeType.Named {
+			grammar.Named[nodeType.Type] = info
+		} else {
+			grammar.Unnamed[nodeType.Type] = info
+		}
+	}
+
+	return grammar
+}
+
+// writeGrammar adds the `Grammar` variable describing the node types, and the
+// `CheckTree` function that checks parsed trees against it.
+func writeGrammar(file *jen.File, nodeTypes nodeTypes, nm *nodeMap) {
+	grammar := buildGrammar(nodeTypes, nm)
+
+	kindList := func(kinds []runtime.SyntaxKind) jen.Code {
+		values := []jen.Code{}
+		for _, kind := range kinds {
+			values = append(values, jen.Lit(kind))
+		}
+		return jen.Index().Qual(runtimePackage, "SyntaxKind").Values(values...)
+	}
+	fieldInfo := func(field runtime.FieldInfo) jen.Dict {
+		dict := jen.Dict{
+			jen.Id("Accessor"): jen.Lit(field.Accessor),
+			jen.Id("Multiple"): jen.Lit(field.Multiple),
+			jen.Id("Required"): jen.Lit(field.Required),
+			jen.Id("Kinds"):    kindList(field.Kinds),
+		}
+		if field.Name != "" {
+			dict[jen.Id("Name")] = jen.Lit(field.Name)
+		}
+		return dict
+	}
+	kindInfos := func(named bool) jen.Code {
+		dict := jen.Dict{}
+		for _, nodeType := range nodeTypes {
+			if nodeType.Subtypes != nil || nodeType.Named != named {
+				continue
+			}
+			info, _ := grammar.Lookup(nodeType.Type, named)
+			fields := []jen.Code{}
+			for _, field := range info.Fields {
+				fields = append(fields, jen.Values(fieldInfo(field)))
+			}
+			infoDict := jen.Dict{
+				jen.Id("Kind"):       jen.Lit(info.Kind),
+				jen.Id("Named"):      jen.Lit(info.Named),
+				jen.Id("StructName"): jen.Lit(info.StructName),
+				jen.Id("Fields"):     jen.Index().Qual(runtimePackage, "FieldInfo").Values(fields...),
+			}
+			if info.Children != nil {
+				infoDict[jen.Id("Children")] = jen.Op("&").Qual(runtimePackage, "FieldInfo").Values(fieldInfo(*info.Children))
+			}
+			dict[jen.Lit(info.Kind)] = jen.Values(infoDict)
+		}
+		return jen.Map(jen.Qual(runtimePackage, "SyntaxKind")).Op("*").Qual(runtimePackage, "KindInfo").Values(dict)
+	}
+
+	supertypes := jen.Dict{}
+	for tsKind, kinds := range grammar.Supertypes {
+		supertypes[jen.Lit(tsKind)] = kindList(kinds)
+	}
+
+	grammarName := nm.names.get("Grammar")
+	checkTreeName := nm.names.get("CheckTree")
+	writeDocComment(
+		file,
+		grammarName+" describes the node types in node-types.json. It's used by `"+checkTreeName+"`, and can be used with the runtime package to check corpus files.",
+	)
+	file.Var().Id(grammarName).Op("=").Op("&").Qual(runtimePackage, "Grammar").Values(jen.Dict{
+		jen.Id("Named"):      kindInfos(true),
+		jen.Id("Unnamed"):    kindInfos(false),
+		jen.Id("Supertypes"): jen.Map(jen.Qual(runtimePackage, "SyntaxKind")).Index().Qual(runtimePackage, "SyntaxKind").Values(supertypes),
+		jen.Id("Extras"):     kindList(grammar.Extras),
+		jen.Id("Root"):       jen.Lit(grammar.Root),
+	})
+
+	tsNode := jen.Op("*").Qual("github.com/tree-sitter/go-tree-sitter", "Node")
+	writeDocComment(
+		file,
+		checkTreeName+" checks that every node in the tree rooted at the given node can be wrapped in its typed node, and that its accessors return the nodes in its fields and children with the kinds and cardinality declared in node-types.json.",
+	)
+	file.Func().Id(checkTreeName).
+		Params(jen.Id("root").Add(tsNode.Clone())).
+		Index().Qual(runtimePackage, "Problem").
+		Block(
+			jen.Return(jen.Qual(runtimePackage, "CheckTree").Call(
+				jen.Id(grammarName),
+				jen.Id("root"),
+				jen.Func().Params(jen.Id("node").Add(tsNode.Clone())).Params(jen.Qual(runtimePackage, "TypedNode"), jen.Error()).Block(
+					jen.Return(jen.Id(nm.names.get("Wrap")).Call(jen.Id("node"))),
+				),
+			)),
+		)
+}
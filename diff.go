@@ -0,0 +1,38 @@
+package gent
+
+import (
+	"github.com/dave/jennifer/jen"
+)
+
+// writeDiffFunction adds the `Diff` function, which compares two trees using the
+// field layout in `Grammar` and wraps the changed nodes in their typed nodes.
+func writeDiffFunction(file *jen.File, nm *nodeMap) {
+	tsNode := jen.Op("*").Qual("github.com/tree-sitter/go-tree-sitter", "Node")
+	diffName := nm.names.get("Diff")
+
+	writeDocComment(
+		file,
+		diffName+" compares the trees rooted at the given nodes, returning the nodes that were inserted, deleted, updated or moved. Children are aligned by the field they're in, so changes are reported against the node they belong to.",
+		"The `Old` and `New` nodes of each edit are typed nodes, e.g. an `*Identifier` for a renamed function.",
+	)
+	file.Func().Id(diffName).
+		Params(
+			jen.Id("oldRoot").Add(tsNode),
+			jen.Id("newRoot").Add(tsNode),
+			jen.Id("oldSource").Index().Byte(),
+			jen.Id("newSource").Index().Byte(),
+		).
+		Op("*").Qual(runtimePackage, "TreeDiff").
+		Block(
+			jen.Return(jen.Qual(runtimePackage, "DiffTrees").Call(
+				jen.Id(nm.names.get("Grammar")),
+				jen.Id("oldRoot"),
+				jen.Id("newRoot"),
+				jen.Id("oldSource"),
+				jen.Id("newSource"),
+				jen.Func().Params(jen.Id("node").Add(tsNode)).Params(jen.Qual(runtimePackage, "TypedNode"), jen.Error()).Block(
+					jen.Return(jen.Id(nm.names.get("Wrap")).Call(jen.Id("node"))),
+				),
+			)),
+		)
+}
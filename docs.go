@@ -0,0 +1,362 @@
+package gent
+
+import (
+	"fmt"
+	"html/template"
+	"slices"
+	"strings"
+)
+
+// docsBackend renders the node type model as reference documentation for the
+// generated Go API, either as Markdown or as a standalone HTML page.
+type docsBackend struct {
+	options GeneratorOptions
+	html    bool
+}
+
+// kindDoc describes a single node kind in the reference documentation.
+type kindDoc struct {
+	kindRef
+	Named     bool
+	Extra     bool
+	Root      bool
+	Supertype bool
+	// From the config file
+	Description string
+	Members     []kindRef
+	Fields      []fieldDoc
+	Children    *fieldDoc
+	Supertypes  []kindRef
+	UsedIn      []usageDoc
+}
+
+// kindRef is a link to the documentation of a node kind.
+type kindRef struct {
+	Kind       string
+	StructName string
+	Anchor     string
+}
+
+type fieldDoc struct {
+	// Empty for children
+	Name      string
+	Signature string
+	Multiple  bool
+	Required  bool
+	Types     []kindRef
+	// From the config file
+	Description string
+}
+
+// usageDoc is a back-reference to a field or the children of another kind in
+// which a kind can appear.
+type usageDoc struct {
+	Parent kindRef
+	// Empty for children
+	Field string
+}
+
+type referenceDoc struct {
+	Title      string
+	Supertypes []kindDoc
+	Nodes      []kindDoc
+	Tokens     []kindDoc
+	Unknown    []kindDoc
+}
+
+func (b *docsBackend) render(nodeTypes nodeTypes, nm *nodeMap) (string, error) {
+	doc, err := buildReferenceDoc(nodeTypes, nm, b.options.Config)
+	if err != nil {
+		return "", err
+	}
+
+	doc.Title = "node_types"
+	if b.options.PackageName != "" {
+		doc.Title = b.options.PackageName
+	}
+
+	if b.html {
+		output := &strings.Builder{}
+		if err := htmlDocsTemplate.Execute(output, doc); err != nil {
+			return "", fmt.Errorf("Failed to render HTML documentation: %w", err)
+		}
+		return output.String(), nil
+	}
+
+	return renderMarkdownDocs(doc), nil
+}
+
+func buildReferenceDoc(nodeTypes nodeTypes, nm *nodeMap, config Config) (referenceDoc, error) {
+	newRef := func(kind string, named bool) (kindRef, error) {
+		structName, ok := nm.getStructName(kind, named)
+		if !ok {
+			return kindRef{}, fmt.Errorf("Failed to find struct name for %s", kind)
+		}
+		return kindRef{Kind: kind, StructName: structName, Anchor: strings.ToLower(structName)}, nil
+	}
+	newRefs := func(types []nodeChildType) ([]kindRef, error) {
+		output := []kindRef{}
+		for _, type_ := range types {
+			ref, err := newRef(type_.Type, type_.Named)
+			if err != nil {
+				return nil, err
+			}
+			output = append(output, ref)
+		}
+		return output, nil
+	}
+
+	// Back-references are keyed by struct name, as named and unnamed kinds can share
+	// the same Tree-sitter name.
+	supertypesOf := map[string][]kindRef{}
+	usedIn := map[string][]usageDoc{}
+
+	doc := referenceDoc{}
+	for _, nodeType := range nodeTypes {
+		ref, err := newRef(nodeType.Type, nodeType.Named)
+		if err != nil {
+			return referenceDoc{}, err
+		}
+		kind := kindDoc{
+			kindRef:   ref,
+			Named:     nodeType.Named,
+			Extra:     nodeType.Extra,
+			Root:      nodeType.Root,
+			Supertype: nodeType.Subtypes != nil,
+
+			Description: config.description(nodeType.Type, ""),
+		}
+
+		if kind.Supertype {
+			kind.Members, err = newRefs(nodeType.Subtypes)
+			if err != nil {
+				return referenceDoc{}, fmt.Errorf("Failed to document %s: %w", nodeType.Type, err)
+			}
+			for _, member := range kind.Members {
+				supertypesOf[member.StructName] = append(supertypesOf[member.StructName], ref)
+			}
+			doc.Supertypes = append(doc.Supertypes, kind)
+			continue
+		}
+
+		for name, field := range nodeType.Fields.FromOldest() {
+			fieldDoc, err := newFieldDoc(name, field, nm)
+			if err != nil {
+				return referenceDoc{}, fmt.Errorf("Failed to document %s.%s: %w", nodeType.Type, name, err)
+			}
+			fieldDoc.Description = config.description(nodeType.Type, name)
+			fieldDoc.Types, err = newRefs(field.Types)
+			if err != nil {
+				return referenceDoc{}, fmt.Errorf("Failed to document %s.%s: %w", nodeType.Type, name, err)
+			}
+			for _, type_ := range fieldDoc.Types {
+				usedIn[type_.StructName] = append(usedIn[type_.StructName], usageDoc{Parent: ref, Field: name})
+			}
+			kind.Fields = append(kind.Fields, fieldDoc)
+		}
+
+		if len(nodeType.Children.Types) > 0 {
+			childrenDoc, err := newFieldDoc("", nodeType.Children, nm)
+			if err != nil {
+				return referenceDoc{}, fmt.Errorf("Failed to document %s children: %w", nodeType.Type, err)
+			}
+			childrenDoc.Types, err = newRefs(nodeType.Children.Types)
+			if err != nil {
+				return referenceDoc{}, fmt.Errorf("Failed to document %s children: %w", nodeType.Type, err)
+			}
+			for _, type_ := range childrenDoc.Types {
+				usedIn[type_.StructName] = append(usedIn[type_.StructName], usageDoc{Parent: ref})
+			}
+			kind.Children = &childrenDoc
+		}
+
+		if nodeType.Named {
+			doc.Nodes = append(doc.Nodes, kind)
+		} else {
+			doc.Tokens = append(doc.Tokens, kind)
+		}
+	}
+
+	for tsKind := range nm.unknown.FromOldest() {
+		ref, err := newRef(tsKind, true)
+		if err != nil {
+			return referenceDoc{}, err
+		}
+		doc.Unknown = append(doc.Unknown, kindDoc{kindRef: ref, Named: true})
+	}
+
+	for _, kinds := range [][]kindDoc{doc.Supertypes, doc.Nodes, doc.Tokens, doc.Unknown} {
+		for i := range kinds {
+			kinds[i].Supertypes = supertypesOf[kinds[i].StructName]
+			kinds[i].UsedIn = usedIn[kinds[i].StructName]
+			slices.SortStableFunc(kinds[i].UsedIn, func(a, b usageDoc) int {
+				return strings.Compare(a.Parent.StructName, b.Parent.StructName)
+			})
+		}
+	}
+
+	return doc, nil
+}
+
+// newFieldDoc documents a field, or the children of a node if name is empty, including
+// the signature of its generated accessor.
+func newFieldDoc(name string, children nodeChildren, nm *nodeMap) (fieldDoc, error) {
+	returnType, ok := nm.getTypeName(children.Types)
+	if !ok {
+		return fieldDoc{}, fmt.Errorf("Failed to find type name")
+	}
+
+	var signature string
+	switch {
+	case name == "" && children.Multiple:
+		signature = fmt.Sprintf("TypedChildren(cursor *tree_sitter.TreeCursor) []%s", returnType)
+	case name == "":
+		signature = fmt.Sprintf("TypedChild(cursor *tree_sitter.TreeCursor) (%s, error)", returnType)
+	case children.Multiple:
+		signature = fmt.Sprintf("%s(cursor *tree_sitter.TreeCursor) []*%s", accessorName(createPrivateName(name)), returnType)
+	default:
+		signature = fmt.Sprintf("%s() (*%s, error)", accessorName(createPrivateName(name)), returnType)
+	}
+
+	return fieldDoc{
+		Name:      name,
+		Signature: signature,
+		Multiple:  children.Multiple,
+		Required:  children.Required,
+	}, nil
+}
+
+func (f fieldDoc) Cardinality() string {
+	return formatCardinality(methodDef{array: f.Multiple, required: f.Required})
+}
+
+func renderMarkdownDocs(doc referenceDoc) string {
+	output := &strings.Builder{}
+	fmt.Fprintf(output, "# `%s` reference\n", doc.Title)
+
+	link := func(ref kindRef) string {
+		return fmt.Sprintf("[`%s`](#%s)", ref.StructName, ref.Anchor)
+	}
+	links := func(refs []kindRef) string {
+		output := []string{}
+		for _, ref := range refs {
+			output = append(output, link(ref))
+		}
+		return strings.Join(output, ", ")
+	}
+	writeKind := func(kind kindDoc) {
+		fmt.Fprintf(output, "\n<a id=\"%s\"></a>\n\n### `%s`\n\n", kind.Anchor, kind.StructName)
+		fmt.Fprintf(output, "Tree-sitter kind: `%s`", kind.Kind)
+		if !kind.Named {
+			output.WriteString(" (unnamed)")
+		}
+		if kind.Root {
+			output.WriteString(" (root)")
+		}
+		if kind.Extra {
+			output.WriteString(" (extra)")
+		}
+		output.WriteString("\n")
+
+		if kind.Description != "" {
+			fmt.Fprintf(output, "\n%s\n", kind.Description)
+		}
+
+		if kind.Supertype {
+			fmt.Fprintf(output, "\nSubtypes: %s\n", links(kind.Members))
+		}
+
+		if len(kind.Fields) > 0 {
+			output.WriteString("\n| Field | Accessor | Cardinality | Kinds | Description |\n| --- | --- | --- | --- | --- |\n")
+			for _, field := range kind.Fields {
+				fmt.Fprintf(
+					output,
+					"| `%s` | `%s` | %s | %s | %s |\n",
+					field.Name,
+					field.Signature,
+					field.Cardinality(),
+					links(field.Types),
+					field.Description,
+				)
+			}
+		}
+
+		if kind.Children != nil {
+			fmt.Fprintf(output, "\nChildren (%s): `%s`\n\n%s\n", kind.Children.Cardinality(), kind.Children.Signature, links(kind.Children.Types))
+		}
+
+		if len(kind.Supertypes) > 0 {
+			fmt.Fprintf(output, "\nMember of: %s\n", links(kind.Supertypes))
+		}
+
+		if len(kind.UsedIn) > 0 {
+			usages := []string{}
+			for _, usage := range kind.UsedIn {
+				if usage.Field == "" {
+					usages = append(usages, link(usage.Parent)+" children")
+				} else {
+					usages = append(usages, fmt.Sprintf("%s.`%s`", link(usage.Parent), usage.Field))
+				}
+			}
+			fmt.Fprintf(output, "\nUsed in: %s\n", strings.Join(usages, ", "))
+		}
+	}
+
+	sections := []struct {
+		title string
+		kinds []kindDoc
+	}{
+		{"Supertypes", doc.Supertypes},
+		{"Nodes", doc.Nodes},
+		{"Tokens", doc.Tokens},
+		{"Unknown types", doc.Unknown},
+	}
+	for _, section := range sections {
+		if len(section.kinds) == 0 {
+			continue
+		}
+		fmt.Fprintf(output, "\n## %s\n", section.title)
+		for _, kind := range section.kinds {
+			writeKind(kind)
+		}
+	}
+
+	return output.String()
+}
+
+var htmlDocsTemplate = template.Must(template.New("docs").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}} reference</title>
+<style>
+body { font-family: sans-serif; max-width: 60em; margin: auto; }
+table { border-collapse: collapse; }
+td, th { border: 1px solid #ccc; padding: 0.25em 0.5em; text-align: left; }
+</style>
+</head>
+<body>
+<h1><code>{{.Title}}</code> reference</h1>
+{{define "refs"}}{{range $i, $ref := .}}{{if $i}}, {{end}}<a href="#{{$ref.Anchor}}"><code>{{$ref.StructName}}</code></a>{{end}}{{end}}
+{{define "kind"}}
+<section id="{{.Anchor}}">
+<h3><code>{{.StructName}}</code></h3>
+<p>Tree-sitter kind: <code>{{.Kind}}</code>{{if not .Named}} (unnamed){{end}}{{if .Root}} (root){{end}}{{if .Extra}} (extra){{end}}</p>
+{{with .Description}}<p>{{.}}</p>{{end}}
+{{if .Supertype}}<p>Subtypes: {{template "refs" .Members}}</p>{{end}}
+{{if .Fields}}<table>
+<tr><th>Field</th><th>Accessor</th><th>Cardinality</th><th>Kinds</th><th>Description</th></tr>
+{{range .Fields}}<tr><td><code>{{.Name}}</code></td><td><code>{{.Signature}}</code></td><td>{{.Cardinality}}</td><td>{{template "refs" .Types}}</td><td>{{.Description}}</td></tr>
+{{end}}</table>{{end}}
+{{with .Children}}<p>Children ({{.Cardinality}}): <code>{{.Signature}}</code></p><p>{{template "refs" .Types}}</p>{{end}}
+{{if .Supertypes}}<p>Member of: {{template "refs" .Supertypes}}</p>{{end}}
+{{if .UsedIn}}<p>Used in: {{range $i, $usage := .UsedIn}}{{if $i}}, {{end}}<a href="#{{$usage.Parent.Anchor}}"><code>{{$usage.Parent.StructName}}</code></a>{{if $usage.Field}}.<code>{{$usage.Field}}</code>{{else}} children{{end}}{{end}}</p>{{end}}
+</section>
+{{end}}
+{{with .Supertypes}}<h2>Supertypes</h2>{{range .}}{{template "kind" .}}{{end}}{{end}}
+{{with .Nodes}}<h2>Nodes</h2>{{range .}}{{template "kind" .}}{{end}}{{end}}
+{{with .Tokens}}<h2>Tokens</h2>{{range .}}{{template "kind" .}}{{end}}{{end}}
+{{with .Unknown}}<h2>Unknown types</h2>{{range .}}{{template "kind" .}}{{end}}{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,32 @@
+package gent
+
+import (
+	"github.com/dave/jennifer/jen"
+)
+
+// writeDumpFunction adds the `Dump` function, which prints a typed node's tree with
+// the names of the generated structs and fields.
+func writeDumpFunction(file *jen.File, nm *nodeMap) {
+	dumpName := nm.names.get("Dump")
+	writeDocComment(
+		file,
+		dumpName+" prints the tree rooted at the given node, one node per line, with the field each node is in, the name of its struct, its range and, for leaves, its text.",
+	)
+	file.Func().Id(dumpName).
+		Params(
+			jen.Id("w").Qual("io", "Writer"),
+			jen.Id("node").Id(nm.names.get("TypedNode")),
+			jen.Id("source").Index().Byte(),
+			jen.Id("options").Qual(runtimePackage, "DumpOptions"),
+		).
+		Error().
+		Block(
+			jen.Return(jen.Qual(runtimePackage, "DumpTree").Call(
+				jen.Id("w"),
+				jen.Id(nm.names.get("Grammar")),
+				jen.Id("node").Dot("AsNode").Call(),
+				jen.Id("source"),
+				jen.Id("options"),
+			)),
+		)
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"reflect"
 	"slices"
+	"strconv"
 	"strings"
 	"unicode"
 
@@ -16,8 +17,26 @@ import (
 	"golang.org/x/text/language"
 )
 
+// Import path of the package containing code shared by all generated packages.
+const runtimePackage = "github.com/isaacharrisholt/gent/runtime"
+
 var caser = cases.Title(language.English)
-var reservedNodeMethods = getNodeMethodNames()
+
+// Methods generated on structs are reserved along with the node's own. Accessors
+// starting with `Append` are renamed too, as they could clash with the `Append`
+// method of another field.
+var reservedNodeMethods = append(
+	getNodeMethodNames(),
+	"AsNode",
+	"ToJSONNode",
+	"MarshalJSON",
+	"LeadingExtras",
+	"TrailingExtras",
+	"AllChildren",
+	"TypedChildren",
+	"TypedChild",
+	"AppendTypedChildren",
+)
 
 type Generator struct {
 	options GeneratorOptions
@@ -28,9 +47,36 @@ type GeneratorOptions struct {
 	// Run the generator in debug mode, adding extra comments to the generated file
 	// and printing debug logs to stderr.
 	Debug bool
+	// The format to render the node types to. Defaults to Go.
+	Format Format
+	// Options for the DOT and Mermaid formats.
+	Graph GraphOptions
+	// Customisations loaded from a config file.
+	Config Config
+	// Import path of the Go bindings of the grammar, e.g.
+	// `github.com/tree-sitter/tree-sitter-python/bindings/go`. If set, the generated
+	// package has `Language`, `Parse` and `ParseFile` functions.
+	Language string
+	// Generate `Append<Field>` methods, which iterate over children with a pooled
+	// cursor and write into caller-supplied buffers instead of allocating.
+	Performance bool
+	// Generate compact code, where accessors delegate to generic helpers in the
+	// runtime package, and kind checks use precomputed sets. `ToJSONNode`,
+	// `LeadingExtras`, `TrailingExtras` and `AllChildren` are package-level functions
+	// rather than methods of every struct, and there are no `MarshalJSON` methods, so
+	// nodes are marshalled with `json.Marshal(ToJSONNode(node, nil))`. This shrinks
+	// the output for large grammars.
+	Compact bool
 	// TODO: Add more options
 }
 
+// resolvedIDs reports whether field and kind IDs are resolved from the bound language
+// when the generated package is initialised. In compact mode, the runtime helpers
+// resolve field IDs on first use instead, and kinds are checked against sets.
+func (o GeneratorOptions) resolvedIDs() bool {
+	return o.Language != "" && !o.Compact
+}
+
 func NewGenerator(options GeneratorOptions) *Generator {
 	return &Generator{
 		options: options,
@@ -42,6 +88,8 @@ type nodeTypes []nodeType
 type nodeType struct {
 	Type     string                                      `json:"type"`
 	Named    bool                                        `json:"named"`
+	Root     bool                                        `json:"root"`
+	Extra    bool                                        `json:"extra"`
 	Fields   orderedmap.OrderedMap[string, nodeChildren] `json:"fields"`
 	Children nodeChildren                                `json:"children"`
 	Subtypes []nodeChildType                             `json:"subtypes"`
@@ -65,21 +113,51 @@ type unionType struct {
 	members []nodeChildType
 }
 
+// Usual name of the union type containing every concrete node kind, named or unnamed.
+const anyNodeStructName = "AnyNode"
+
 type methodDef struct {
 	methodName  string
 	tsFieldName string
 	returnType  string
 	array       bool
+	required    bool
 	tsKinds     []string
+	// Structs of the concrete kinds in tsKinds, whose kind ID variables union accessors
+	// compare against
+	structNames []string
+	// Extra description from the config, added to the doc comment
+	description string
 }
 
 type structDef struct {
 	name string
+	// Paragraphs of the struct's doc comment
+	doc []string
 	// Tree-sitter node kind
 	tsKind            string
 	methods           []methodDef
 	isUnionType       bool
 	childrenMethodDef *methodDef
+	// Struct name returned by the `LeadingExtras` and `TrailingExtras` methods.
+	// If empty, the methods aren't generated.
+	extrasReturnType string
+	// Struct name returned by the `AllChildren` method, which returns every child
+	// including unnamed tokens. If empty, the method isn't generated.
+	allChildrenReturnType string
+	// Name of the `Wrap` function, which union types use to convert their node into
+	// JSON through its concrete type.
+	wrapName string
+	// Whether to generate allocation-free `Append<Field>` methods for fields and
+	// children that can hold multiple nodes.
+	appendMethods bool
+	// Whether the field and kind ID variables hold IDs resolved from a bound language,
+	// rather than field IDs being `runtime.FieldID` values resolved on first use and
+	// kinds being compared by name.
+	resolvedIDs bool
+	// Whether accessors delegate to the generic helpers in the runtime package, rather
+	// than being inlined.
+	compact bool
 }
 
 // Inner map keys are all Tree-sitter node names
@@ -105,6 +183,18 @@ type nodeMap struct {
 	// them.
 	// Values are struct names.
 	unknown *orderedmap.OrderedMap[string, string]
+	// Whether each unknown type was referenced as a named kind, to look up its ID.
+	unknownNamed map[string]bool
+
+	// Node types marked with `"extra": true`, e.g. comments. These can appear anywhere
+	// in the tree, so they're never part of `fields` or `children`.
+	extras []nodeChildType
+
+	// The Tree-sitter node name of the type marked with `"root": true`, if any.
+	root string
+
+	// Package-level names, so that helpers can be named around the node types.
+	names *packageNames
 }
 
 func newNodeMap() nodeMap {
@@ -114,11 +204,19 @@ func newNodeMap() nodeMap {
 		supertypes:      orderedmap.New[string, unionType](),
 		unionTypes:      orderedmap.New[string, unionType](),
 		unknown:         orderedmap.New[string, string](),
+		unknownNamed:    map[string]bool{},
+		names:           newPackageNames(),
 	}
 }
 
 func (nm *nodeMap) registerNodeType(nodeType nodeType) {
 	structName := generateStructName(nodeType.Type, nodeType.Named)
+	if nodeType.Extra {
+		nm.extras = append(nm.extras, nodeChildType{Type: nodeType.Type, Named: nodeType.Named})
+	}
+	if nodeType.Root {
+		nm.root = nodeType.Type
+	}
 	if nodeType.Named {
 		nm.namedExported.Set(nodeType.Type, structName)
 		return
@@ -204,9 +302,49 @@ func (nm *nodeMap) getUnionType(types []nodeChildType) (unionType, bool) {
 	return ut, true
 }
 
-func (nm *nodeMap) registerUnknownType(typeName string) {
+// getTSRecursiveTSKindsOf returns the concrete Tree-sitter kinds a value of any of the
+// given types can have.
+func (nm *nodeMap) getTSRecursiveTSKindsOf(types []nodeChildType) []string {
+	tsKinds := []string{}
+	for _, type_ := range types {
+		for _, tsKind := range nm.getTSRecursiveTSKinds(type_.Type) {
+			if !slices.Contains(tsKinds, tsKind) {
+				tsKinds = append(tsKinds, tsKind)
+			}
+		}
+	}
+	return tsKinds
+}
+
+// getTypeName returns the name of the struct used to represent a value that can be any
+// of the given types. This is the type's own struct if there's only one, otherwise it's
+// a union type.
+func (nm *nodeMap) getTypeName(types []nodeChildType) (string, bool) {
+	if len(types) == 1 {
+		return nm.getStructName(types[0].Type, types[0].Named)
+	}
+	ut, ok := nm.getUnionType(types)
+	return ut.name, ok
+}
+
+// getExtrasStructName returns the name of the struct used to represent extra nodes.
+// If there's only one extra type, that type's struct is used directly, otherwise
+// a union type of all the extras is used.
+func (nm *nodeMap) getExtrasStructName() (string, bool) {
+	if len(nm.extras) == 0 {
+		return "", false
+	}
+	if len(nm.extras) == 1 {
+		return nm.getStructName(nm.extras[0].Type, nm.extras[0].Named)
+	}
+	ut, ok := nm.getUnionType(nm.extras)
+	return ut.name, ok
+}
+
+func (nm *nodeMap) registerUnknownType(typeName string, named bool) {
 	structName := "Unknown__" + createPrivateName(typeName)
 	nm.unknown.Set(typeName, structName)
+	nm.unknownNamed[typeName] = named
 }
 
 // Method definitions describe what methods are available on the generated
@@ -246,28 +384,44 @@ func (nm *nodeMap) getTSRecursiveTSKinds(typeName string) []string {
 	return tsKinds
 }
 
-func (g *Generator) Generate(data []byte) (string, error) {
-	var nodeTypes nodeTypes
-	err := json.Unmarshal(data, &nodeTypes)
-	if err != nil {
-		return "", fmt.Errorf("Failed to unmarshal JSON: %w", err)
-	}
+// getRecursiveStructNames returns the structs of the concrete kinds a value of any of
+// the given types can have. Unlike getTSRecursiveTSKinds, named and unnamed kinds
+// sharing a name are told apart.
+func (nm *nodeMap) getRecursiveStructNames(types []nodeChildType) []string {
+	typesToCheck := slices.Clone(types)
+	structNames := []string{}
+	for len(typesToCheck) > 0 {
+		type_ := typesToCheck[0]
+		typesToCheck = typesToCheck[1:]
 
-	nodeTypeMap := make(map[string]nodeType)
-	for _, nodeType := range nodeTypes {
-		nodeTypeMap[nodeType.Type] = nodeType
+		if type_.Named {
+			if superType, ok := nm.supertypes.Get(type_.Type); ok {
+				typesToCheck = append(typesToCheck, superType.members...)
+				continue
+			}
+		}
+		structName, ok := nm.getStructName(type_.Type, type_.Named)
+		if ok && !slices.Contains(structNames, structName) {
+			structNames = append(structNames, structName)
+		}
 	}
+	return structNames
+}
 
-	packageName := "node_types"
-	if g.options.PackageName != "" {
-		packageName = g.options.PackageName
+func parseNodeTypes(data []byte) (nodeTypes, error) {
+	var nodeTypes nodeTypes
+	err := json.Unmarshal(data, &nodeTypes)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal JSON: %w", err)
 	}
+	return nodeTypes, nil
+}
 
-	file := jen.NewFile(packageName)
-
-	file.ImportName("github.com/tree-sitter/go-tree-sitter", "tree_sitter")
-
+// buildNodeMap registers all the node types, supertypes, union types and unknown
+// types used in the given node types.
+func buildNodeMap(nodeTypes nodeTypes) (nodeMap, error) {
 	nm := newNodeMap()
+	var err error
 
 	// Get all the node types available in the file
 	for _, nodeType := range nodeTypes {
@@ -289,7 +443,7 @@ func (g *Generator) Generate(data []byte) (string, error) {
 					// from the types in the field.
 					err = nm.registerUnionType(children.Types)
 					if err != nil {
-						return "", fmt.Errorf("Failed to register a union type for %s.%s: %w", nodeType.Type, name, err)
+						return nodeMap{}, fmt.Errorf("Failed to register a union type for %s.%s: %w", nodeType.Type, name, err)
 					}
 				}
 			}
@@ -297,12 +451,20 @@ func (g *Generator) Generate(data []byte) (string, error) {
 			if len(nodeType.Children.Types) > 1 {
 				err = nm.registerUnionType(nodeType.Children.Types)
 				if err != nil {
-					return "", fmt.Errorf("Failed to register a union type for %s children: %w", nodeType.Type, err)
+					return nodeMap{}, fmt.Errorf("Failed to register a union type for %s children: %w", nodeType.Type, err)
 				}
 			}
 		}
 	}
 
+	// Extras can be of multiple types, in which case they need a union type too.
+	if len(nm.extras) > 1 {
+		err = nm.registerUnionType(nm.extras)
+		if err != nil {
+			return nodeMap{}, fmt.Errorf("Failed to register a union type for extras: %w", err)
+		}
+	}
+
 	// Check through again to see if there are any types used in fields/children that
 	// aren't defined. At time of writing, Python node-types.json uses `as_pattern_target`
 	// which is never declared.
@@ -313,7 +475,7 @@ func (g *Generator) Generate(data []byte) (string, error) {
 			for _, fieldType := range field.Types {
 				_, ok := nm.getStructName(fieldType.Type, fieldType.Named)
 				if !ok {
-					nm.registerUnknownType(fieldType.Type)
+					nm.registerUnknownType(fieldType.Type, fieldType.Named)
 				}
 			}
 		}
@@ -321,36 +483,85 @@ func (g *Generator) Generate(data []byte) (string, error) {
 		for _, childType := range nodeType.Children.Types {
 			_, ok := nm.getStructName(childType.Type, childType.Named)
 			if !ok {
-				nm.registerUnknownType(childType.Type)
+				nm.registerUnknownType(childType.Type, childType.Named)
 			}
 		}
 	}
 
+	err = nm.reserveKindNames(nodeTypes)
+	if err != nil {
+		return nodeMap{}, fmt.Errorf("Failed to name the node types: %w", err)
+	}
+
+	return nm, nil
+}
+
+// Generate generates code from the given node-types.json data in the format set in
+// the generator's options.
+func (g *Generator) Generate(data []byte) (string, error) {
+	nodeTypes, err := parseNodeTypes(data)
+	if err != nil {
+		return "", err
+	}
+
+	nm, err := buildNodeMap(nodeTypes)
+	if err != nil {
+		return "", err
+	}
+
 	if g.options.Debug {
 		fmt.Println(nm)
 	}
 
+	backend, err := g.backend()
+	if err != nil {
+		return "", err
+	}
+
+	return backend.render(nodeTypes, &nm)
+}
+
+// goBackend renders the node type model as Go code using jennifer.
+type goBackend struct {
+	options GeneratorOptions
+}
+
+func (b *goBackend) render(nodeTypes nodeTypes, nm *nodeMap) (string, error) {
+	var err error
+
+	packageName := "node_types"
+	if b.options.PackageName != "" {
+		packageName = b.options.PackageName
+	}
+
+	file := jen.NewFile(packageName)
+
+	file.ImportName("github.com/tree-sitter/go-tree-sitter", "tree_sitter")
+	file.ImportName(runtimePackage, "runtime")
+
 	// Create an enum for the public node types
-	file.Type().Id("SyntaxKind").Op("=").String()
+	syntaxKindName := nm.names.get("SyntaxKind")
+	writeDocComment(file, syntaxKindName+" is the kind of a Tree-sitter node, as returned by `Node.Kind`.")
+	file.Type().Id(syntaxKindName).Op("=").String()
 	var publicTypes []jen.Code
 
 	addPublicType := func(name string, tsKindName string) {
-		publicTypes = append(publicTypes, jen.Id("SyntaxKind_"+name).Id("SyntaxKind").Op("=").Lit(tsKindName))
+		publicTypes = append(publicTypes, jen.Id(syntaxKindConstName(name)).Id(syntaxKindName).Op("=").Lit(tsKindName))
 	}
 
-	if g.options.Debug {
+	if b.options.Debug {
 		publicTypes = append(publicTypes, file.Comment("Named types"))
 	}
 	for tsKindName, structName := range nm.namedExported.FromOldest() {
 		addPublicType(structName, tsKindName)
 	}
-	if g.options.Debug {
+	if b.options.Debug {
 		publicTypes = append(publicTypes, file.Comment("Unnamed types"))
 	}
 	for tsKindName, structName := range nm.unnamedExported.FromOldest() {
 		addPublicType(structName, tsKindName)
 	}
-	if g.options.Debug {
+	if b.options.Debug {
 		publicTypes = append(publicTypes, file.Comment("Supertypes"))
 	}
 	for tsKindName, unionType := range nm.supertypes.FromOldest() {
@@ -359,7 +570,38 @@ func (g *Generator) Generate(data []byte) (string, error) {
 
 	file.Var().Defs(publicTypes...)
 
-	if g.options.Debug {
+	if b.options.Performance || b.options.Language != "" || b.options.Compact {
+		writeFieldIDs(file, nodeTypes, nm, b.options.resolvedIDs())
+	}
+	if b.options.Compact {
+		writeKindSets(file, nodeTypes, nm)
+	}
+	if b.options.Language != "" {
+		writeKindIDs(file, nodeTypes, nm)
+	}
+
+	// Add metadata about extra and root node kinds
+	extraKinds := []jen.Code{}
+	for _, extra := range nm.extras {
+		structName, _ := nm.getStructName(extra.Type, extra.Named)
+		extraKinds = append(extraKinds, jen.Id(syntaxKindConstName(structName)))
+	}
+	extraKindsName := nm.names.get("ExtraSyntaxKinds")
+	isExtraKindName := nm.names.get("IsExtraSyntaxKind")
+	writeDocComment(file, extraKindsName+" contains the kinds of node that can appear anywhere in the tree, such as comments.")
+	file.Var().Id(extraKindsName).Op("=").Index().Id(syntaxKindName).Values(extraKinds...)
+	writeDocComment(file, isExtraKindName+" reports whether the given kind is an extra.")
+	file.Func().Id(isExtraKindName).Params(jen.Id("kind").Id(syntaxKindName)).Bool().Block(
+		jen.Return(jen.Qual("slices", "Contains").Call(jen.Id(extraKindsName), jen.Id("kind"))),
+	)
+	if nm.root != "" {
+		rootStructName, _ := nm.getStructName(nm.root, true)
+		rootKindName := nm.names.get("RootSyntaxKind")
+		writeDocComment(file, rootKindName+" is the kind of the root node of every tree.")
+		file.Var().Id(rootKindName).Op("=").Id(syntaxKindConstName(rootStructName))
+	}
+
+	if b.options.Debug {
 		file.Comment("\nGENERAL NODES\n")
 	}
 	for _, nodeType := range nodeTypes {
@@ -367,45 +609,128 @@ func (g *Generator) Generate(data []byte) (string, error) {
 			continue
 		}
 
-		if g.options.Debug {
+		if b.options.Debug {
 			fmt.Printf("Adding node type %s\n", nodeType.Type)
 		}
-		err := addNodeType(file, nodeType, &nm)
+		err := addNodeType(file, nodeType, nm, b.options)
 		if err != nil {
 			return "", fmt.Errorf("Failed to add node type %s: %w", nodeType.Type, err)
 		}
 	}
 
-	if g.options.Debug {
+	if b.options.Debug {
 		file.Comment("\nSUPERTYPES\n")
 	}
-	for _, supertype := range nm.supertypes.FromOldest() {
-		err := addUnionType(file, supertype, &nm)
+	for tsKind, supertype := range nm.supertypes.FromOldest() {
+		doc := []string{
+			fmt.Sprintf(
+				"%s is the %q supertype, which can be any of the following kinds: %s.",
+				supertype.name,
+				tsKind,
+				formatKinds(nm.getTSRecursiveTSKindsOf(supertype.members)),
+			),
+			b.options.Config.description(tsKind, ""),
+		}
+		err := addUnionType(file, supertype, nm, doc, b.options)
 		if err != nil {
 			return "", fmt.Errorf("Failed to add supertype %s: %w", supertype.name, err)
 		}
 	}
 
-	if g.options.Debug {
+	if b.options.Debug {
 		file.Comment("\nUNION TYPES\n")
 	}
 	for _, unionType := range nm.unionTypes.FromOldest() {
-		err := addUnionType(file, unionType, &nm)
+		doc := []string{fmt.Sprintf(
+			"%s can be any of the following kinds: %s.",
+			unionType.name,
+			formatKinds(nm.getTSRecursiveTSKindsOf(unionType.members)),
+		)}
+		err := addUnionType(file, unionType, nm, doc, b.options)
 		if err != nil {
 			return "", fmt.Errorf("Failed to add union type %s: %w", unionType.name, err)
 		}
 	}
 
+	if b.options.Debug {
+		file.Comment("\nANY NODE\n")
+	}
+	err = addAnyNodeType(file, nodeTypes, nm, b.options)
+	if err != nil {
+		return "", fmt.Errorf("Failed to add %s type: %w", anyNodeStructName, err)
+	}
+
+	// Every struct has a constructor
+	constructedStructs := []string{}
+	for _, nodeType := range nodeTypes {
+		if nodeType.Subtypes != nil {
+			continue
+		}
+		structName, _ := nm.getStructName(nodeType.Type, nodeType.Named)
+		constructedStructs = append(constructedStructs, structName)
+	}
+	for _, supertype := range nm.supertypes.FromOldest() {
+		constructedStructs = append(constructedStructs, supertype.name)
+	}
+	for _, unionType := range nm.unionTypes.FromOldest() {
+		constructedStructs = append(constructedStructs, unionType.name)
+	}
+	constructedStructs = append(constructedStructs, nm.names.getStruct(anyNodeStructName))
+	for _, structName := range nm.unknown.FromOldest() {
+		constructedStructs = append(constructedStructs, structName)
+	}
+	writeCastFunctions(file, constructedStructs, nm, b.options.Compact)
+	writeEqualFunctions(file, nm)
+	writePathOfFunction(file, nm)
+	if b.options.Compact {
+		writeCompactFunctions(file, nm)
+	}
+	writeWrapFunction(file, nodeTypes, nm)
+	writeGrammar(file, nodeTypes, nm)
+	writeDiffFunction(file, nm)
+	writeResolveFunction(file, nm)
+	writeSelectorFunctions(file, nm)
+	writeDumpFunction(file, nm)
+	writeUnparserFunction(file, nm)
+	if len(b.options.Config.Roles.Scopes) > 0 {
+		err = writeScopeFunctions(file, nodeTypes, b.options.Config.Roles, nm)
+		if err != nil {
+			return "", fmt.Errorf("Failed to add scope functions: %w", err)
+		}
+	}
+	writeVisitorFunctions(file, nodeTypes, nm)
+	err = writeMatchFunctions(file, nodeTypes, nm)
+	if err != nil {
+		return "", fmt.Errorf("Failed to add match functions: %w", err)
+	}
+
+	if b.options.Language != "" {
+		err = writeLanguageFunctions(file, b.options.Language, nm)
+		if err != nil {
+			return "", fmt.Errorf("Failed to add language functions: %w", err)
+		}
+	}
+
+	// Named last, so that the other helpers keep their names if a field shares one
+	writeFieldMatchFunctions(file, nodeTypes, nm)
+
 	// Add empty structs for the unknown types. They can be private.
-	if g.options.Debug {
+	if b.options.Debug {
 		file.Comment("\nUNKNOWN TYPES\n")
 	}
 	for tsKind, unknownType := range nm.unknown.FromOldest() {
 		writeStruct(file, structDef{
-			name:    unknownType,
+			name: unknownType,
+			doc: []string{fmt.Sprintf(
+				"%s wraps nodes of kind %q, which is used in node-types.json but never declared.",
+				unknownType,
+				tsKind,
+			)},
 			tsKind:  tsKind,
 			methods: []methodDef{},
+			compact: b.options.Compact,
 		})
+		writeConstructor(file, unknownType, tsKind, b.options)
 	}
 
 	outputBuilder := &strings.Builder{}
@@ -427,7 +752,7 @@ func generateStructName(typeName string, named bool) string {
 	return structName
 }
 
-func addNodeType(file *jen.File, nodeType nodeType, nm *nodeMap) error {
+func addNodeType(file *jen.File, nodeType nodeType, nm *nodeMap, options GeneratorOptions) error {
 	structName, ok := nm.getStructName(nodeType.Type, nodeType.Named)
 	if !ok {
 		return fmt.Errorf("Failed to find struct name for %s", nodeType.Type)
@@ -453,7 +778,7 @@ func addNodeType(file *jen.File, nodeType nodeType, nm *nodeMap) error {
 				return fmt.Errorf("Failed to find union type for %s.%s types", nodeType.Type, name)
 			}
 			typeName = unionType.name
-			tsKinds = append(tsKinds, nm.getTSRecursiveTSKinds(unionType.name)...)
+			tsKinds = append(tsKinds, nm.getTSRecursiveTSKindsOf(field.Types)...)
 		}
 
 		methodDefs = append(methodDefs, methodDef{
@@ -461,7 +786,9 @@ func addNodeType(file *jen.File, nodeType nodeType, nm *nodeMap) error {
 			tsFieldName: name,
 			returnType:  typeName,
 			array:       field.Multiple,
+			required:    field.Required,
 			tsKinds:     tsKinds,
+			description: options.Config.description(nodeType.Type, name),
 		})
 	}
 
@@ -484,6 +811,7 @@ func addNodeType(file *jen.File, nodeType nodeType, nm *nodeMap) error {
 				tsFieldName: child.Type,
 				returnType:  childNodeName,
 				array:       nodeType.Children.Multiple,
+				required:    nodeType.Children.Required,
 				tsKinds:     nm.getTSRecursiveTSKinds(child.Type),
 			}
 		} else {
@@ -491,28 +819,76 @@ func addNodeType(file *jen.File, nodeType nodeType, nm *nodeMap) error {
 			if !ok {
 				return fmt.Errorf("Failed to find union type name for children of %s", nodeType.Type)
 			}
-			tsKinds := nm.getTSRecursiveTSKinds(unionType.name)
+			tsKinds := nm.getTSRecursiveTSKindsOf(nodeType.Children.Types)
 			childrenMethodDef = &methodDef{
 				methodName: methodName,
 				returnType: unionType.name,
 				array:      nodeType.Children.Multiple,
+				required:   nodeType.Children.Required,
 				tsKinds:    tsKinds,
 			}
 		}
 	}
 
+	doc := []string{fmt.Sprintf("%s wraps a Tree-sitter node of kind %q.", structName, nodeType.Type)}
+	if !nodeType.Named {
+		doc[0] = fmt.Sprintf("%s wraps an unnamed Tree-sitter node of kind %q.", structName, nodeType.Type)
+	}
+	if nodeType.Extra {
+		doc = append(doc, "Nodes of this kind are extras, so they can appear anywhere in the tree.")
+	}
+	doc = append(doc, options.Config.description(nodeType.Type, ""))
+
+	// Unnamed tokens are never asked for their comments, so they don't get extras
+	// accessors
+	extrasStructName := ""
+	if nodeType.Named {
+		extrasStructName, _ = nm.getExtrasStructName()
+	}
 	writeStruct(file, structDef{
-		name:              structName,
-		tsKind:            nodeType.Type,
-		methods:           methodDefs,
-		isUnionType:       false,
-		childrenMethodDef: childrenMethodDef,
+		name:                  structName,
+		doc:                   doc,
+		tsKind:                nodeType.Type,
+		methods:               methodDefs,
+		isUnionType:           false,
+		childrenMethodDef:     childrenMethodDef,
+		extrasReturnType:      extrasStructName,
+		allChildrenReturnType: nm.names.getStruct(anyNodeStructName),
+		appendMethods:         options.Performance,
+		resolvedIDs:           options.resolvedIDs(),
+		compact:               options.Compact,
 	})
 
-	// Create 'New*' function for creating a new struct given the tree-sitter node
+	writeConstructor(file, structName, nodeType.Type, options)
+	return nil
+}
+
+// writeConstructor creates a 'New*' function for a struct wrapping a single kind,
+// which returns an error if the node is of any other kind.
+func writeConstructor(file *jen.File, structName string, tsKind string, options GeneratorOptions) {
+	kindCheck := jen.Id("node").Dot("Kind").Call().Op("!=").Lit(tsKind)
+	if options.Language != "" {
+		kindCheck = jen.Id("node").Dot("KindId").Call().Op("!=").Id(kindIDVarName(structName))
+	}
+	writeDocComment(file, fmt.Sprintf(
+		"%s creates a %s from the given node, returning an error if the node isn't of kind %q.",
+		constructorName(structName),
+		structName,
+		tsKind,
+	))
+	if options.Compact {
+		file.Func().
+			Id(constructorName(structName)).
+			Params(jen.Id("node").Op("*").Qual("github.com/tree-sitter/go-tree-sitter", "Node")).
+			Parens(jen.List(jen.Op("*").Id(structName), jen.Error())).
+			Block(jen.Return(
+				jen.Qual(runtimePackage, "As").Types(jen.Id(structName)).Call(jen.Id("node"), jen.Id(kindSetVarName(structName))),
+			))
+		return
+	}
 	file.
 		Func().
-		Id("New"+upperFirst(structName)).
+		Id(constructorName(structName)).
 		Parens(
 			jen.Id("node").Op("*").Qual("github.com/tree-sitter/go-tree-sitter", "Node"),
 		).
@@ -523,15 +899,13 @@ func addNodeType(file *jen.File, nodeType nodeType, nm *nodeMap) error {
 			),
 		).
 		Block(
-			jen.If(
-				jen.Id("node").Dot("Kind").Call().Op("!=").Lit(nodeType.Type),
-			).
+			jen.If(kindCheck).
 				Block(
 					jen.Return(
 						jen.Nil(),
-						jen.Qual("fmt", "Errorf").Call(
-							jen.Lit("Node is not a %s"),
-							jen.Lit(nodeType.Type),
+						jen.Qual(runtimePackage, "NewKindMismatchError").Call(
+							jen.Index().String().Values(jen.Lit(tsKind)),
+							jen.Id("node"),
 						),
 					),
 				),
@@ -544,38 +918,375 @@ func addNodeType(file *jen.File, nodeType nodeType, nm *nodeMap) error {
 				jen.Nil(),
 			),
 		)
-
-	return nil
 }
 
-func addUnionType(file *jen.File, unionType unionType, nm *nodeMap) error {
+func addUnionType(file *jen.File, unionType unionType, nm *nodeMap, doc []string, options GeneratorOptions) error {
 	// A union type is a struct containing fields for each of the types in the
 	// union. These are all pointers to indicate that any of them could be nil.
 	// The types of the fields should always be exported.
 	methodDefs := []methodDef{}
+	tsKinds := []string{}
+	structNames := []string{}
 	for _, member := range unionType.members {
 		typeName, ok := nm.getStructName(member.Type, member.Named)
 		if !ok {
 			return fmt.Errorf("Failed to find struct name for %s.%s", unionType.name, member.Type)
 		}
+		memberTSKinds := nm.getTSRecursiveTSKinds(member.Type)
+		memberStructNames := nm.getRecursiveStructNames([]nodeChildType{member})
 		methodDefs = append(methodDefs, methodDef{
 			methodName:  createPrivateName(member.Type),
 			tsFieldName: member.Type,
 			returnType:  typeName,
 			array:       false,
-			tsKinds:     nm.getTSRecursiveTSKinds(member.Type),
+			tsKinds:     memberTSKinds,
+			structNames: memberStructNames,
+		})
+		tsKinds = append(tsKinds, memberTSKinds...)
+		structNames = append(structNames, memberStructNames...)
+	}
+
+	extrasStructName, _ := nm.getExtrasStructName()
+	writeStruct(file, structDef{
+		name:                  unionType.name,
+		doc:                   doc,
+		methods:               methodDefs,
+		isUnionType:           true,
+		wrapName:              nm.names.get("Wrap"),
+		extrasReturnType:      extrasStructName,
+		allChildrenReturnType: nm.names.getStruct(anyNodeStructName),
+		resolvedIDs:           options.resolvedIDs(),
+		compact:               options.Compact,
+	})
+	writeUnionConstructor(file, unionType.name, tsKinds, structNames, options)
+
+	return nil
+}
+
+// addAnyNodeType adds the `AnyNode` union type, which can hold any concrete node kind,
+// including unnamed tokens like operators and keywords. node-types.json doesn't
+// record which unnamed tokens can appear in which nodes, so `AllChildren` has to
+// return this type.
+//
+// Member methods are named after the member's struct rather than its Tree-sitter kind,
+// as named and unnamed kinds often share the same name (e.g. `await`).
+func addAnyNodeType(file *jen.File, nodeTypes nodeTypes, nm *nodeMap, options GeneratorOptions) error {
+	structName := nm.names.getStruct(anyNodeStructName)
+	methodDefs := []methodDef{}
+	tsKinds := []string{}
+	structNames := []string{}
+	for _, nodeType := range nodeTypes {
+		if nodeType.Subtypes != nil {
+			continue
+		}
+		typeName, ok := nm.getStructName(nodeType.Type, nodeType.Named)
+		if !ok {
+			return fmt.Errorf("Failed to find struct name for %s", nodeType.Type)
+		}
+		methodDefs = append(methodDefs, methodDef{
+			methodName:  typeName,
+			tsFieldName: nodeType.Type,
+			returnType:  typeName,
+			array:       false,
+			tsKinds:     []string{nodeType.Type},
+			structNames: []string{typeName},
 		})
+		tsKinds = append(tsKinds, nodeType.Type)
+		structNames = append(structNames, typeName)
 	}
 
+	extrasStructName, _ := nm.getExtrasStructName()
 	writeStruct(file, structDef{
-		name:        unionType.name,
-		methods:     methodDefs,
-		isUnionType: true,
+		name: structName,
+		doc: []string{
+			structName + " can be any kind of node, named or unnamed. It's returned by `AllChildren`, " +
+				"as node-types.json doesn't record which unnamed tokens each kind of node can contain.",
+		},
+		methods:               methodDefs,
+		isUnionType:           true,
+		wrapName:              nm.names.get("Wrap"),
+		extrasReturnType:      extrasStructName,
+		allChildrenReturnType: nm.names.getStruct(anyNodeStructName),
+		resolvedIDs:           options.resolvedIDs(),
+		compact:               options.Compact,
 	})
+	writeUnionConstructor(file, structName, tsKinds, structNames, options)
 
 	return nil
 }
 
+// writeUnionConstructor creates a 'New*' function for a supertype or union type,
+// which accepts a node of any of the given Tree-sitter kinds. If the package is bound
+// to a language, the kinds are checked by the IDs of their structs.
+func writeUnionConstructor(file *jen.File, structName string, tsKinds []string, structNames []string, options GeneratorOptions) {
+	if options.Compact {
+		writeDocComment(file, fmt.Sprintf(
+			"%s creates a %s from the given node, returning an error if the node isn't one of its kinds.",
+			constructorName(structName),
+			structName,
+		))
+		file.Func().
+			Id(constructorName(structName)).
+			Params(jen.Id("node").Op("*").Qual("github.com/tree-sitter/go-tree-sitter", "Node")).
+			Parens(jen.List(jen.Op("*").Id(structName), jen.Error())).
+			Block(jen.Return(
+				jen.Qual(runtimePackage, "As").Types(jen.Id(structName)).Call(jen.Id("node"), jen.Id(kindSetVarName(structName))),
+			))
+		return
+	}
+
+	tsKindsVarName := "tsKinds"
+	tsKindsArray := []jen.Code{}
+	for _, tsKind := range tsKinds {
+		tsKindsArray = append(tsKindsArray, jen.Lit(tsKind))
+	}
+	newStruct := jen.Return(
+		jen.Op("&").Id(structName).Values(
+			jen.Dict{
+				jen.Id("Node"): jen.Op("*").Id("node"),
+			},
+		),
+		jen.Nil(),
+	)
+
+	body := []jen.Code{
+		jen.Id(tsKindsVarName).Op(":=").Index().String().Values(tsKindsArray...),
+		jen.If(
+			jen.Op("!").Qual("slices", "Contains").Call(jen.Id(tsKindsVarName), jen.Id("node").Dot("Kind").Call()),
+		).
+			Block(
+				jen.Return(
+					jen.Nil(),
+					jen.Qual(runtimePackage, "NewKindMismatchError").Call(
+						jen.Id(tsKindsVarName),
+						jen.Id("node"),
+					),
+				),
+			),
+		newStruct,
+	}
+	if options.resolvedIDs() {
+		// Compare integers, only listing the kinds when reporting a mismatch
+		body = []jen.Code{
+			kindIDSwitch(jen.Id("node"), structNames, newStruct),
+			jen.Return(
+				jen.Nil(),
+				jen.Qual(runtimePackage, "NewKindMismatchError").Call(
+					jen.Index().String().Values(tsKindsArray...),
+					jen.Id("node"),
+				),
+			),
+		}
+	}
+
+	writeDocComment(file, fmt.Sprintf(
+		"%s creates a %s from the given node, returning an error if the node isn't one of its kinds.",
+		constructorName(structName),
+		structName,
+	))
+
+	file.
+		Func().
+		Id(constructorName(structName)).
+		Parens(
+			jen.Id("node").Op("*").Qual("github.com/tree-sitter/go-tree-sitter", "Node"),
+		).
+		Parens(
+			jen.List(
+				jen.Op("*").Id(structName),
+				jen.Error(),
+			),
+		).
+		Block(body...)
+}
+
+// kindIDSwitch returns a switch on the kind ID of node that runs body if it's one of the
+// kinds of the given structs.
+func kindIDSwitch(node *jen.Statement, structNames []string, body ...jen.Code) jen.Code {
+	ids := []jen.Code{}
+	for _, structName := range structNames {
+		ids = append(ids, jen.Id(kindIDVarName(structName)))
+	}
+	return jen.Switch(node.Dot("KindId").Call()).Block(jen.Case(ids...).Block(body...))
+}
+
+// writeCastFunctions adds the `TypedNode` interface, an `init` function registering the
+// constructor of every struct that has one with the runtime package, and the generic
+// `Cast` and `MustCast` functions that use the registry to create a typed node without
+// knowing the name of its constructor.
+func writeCastFunctions(file *jen.File, structNames []string, nm *nodeMap, compact bool) {
+	typedNodeName := nm.names.get("TypedNode")
+	castName := nm.names.get("Cast")
+	mustCastName := nm.names.get("MustCast")
+	nodeType := jen.Op("*").Qual("github.com/tree-sitter/go-tree-sitter", "Node")
+
+	methods := []jen.Code{
+		jen.Comment("AsNode returns the underlying Tree-sitter node."),
+		jen.Id("AsNode").Params().Add(nodeType.Clone()),
+	}
+	if !compact {
+		methods = append(methods,
+			jen.Comment("ToJSONNode converts the node into its JSON representation."),
+			jen.Id("ToJSONNode").Params(jen.Id("source").Index().Byte()).Op("*").Qual(runtimePackage, "JSONNode"),
+		)
+	}
+	writeDocComment(file, typedNodeName+" is implemented by every generated node type.")
+	file.Type().Id(typedNodeName).Interface(methods...)
+
+	registrations := []jen.Code{}
+	for _, structName := range structNames {
+		registrations = append(registrations, jen.Qual(runtimePackage, "Register").Call(jen.Id(constructorName(structName))))
+	}
+	writeDocComment(file, "Register the constructor of every typed node, so that they can be used with "+castName+
+		" and the generic helpers in the runtime package, such as `runtime.Field`.")
+	file.Func().Id("init").Params().Block(registrations...)
+
+	writeDocComment(file, castName+" creates a typed node of type T from the given node, returning an error if the node's kind can't be represented by T.")
+	file.Func().Id(castName).Types(jen.Id("T").Id(typedNodeName)).
+		Params(jen.Id("node").Add(nodeType.Clone())).
+		Params(jen.Id("T"), jen.Error()).
+		Block(
+			jen.Var().Id("zero").Id("T"),
+			// Constructors are registered by the type they create a pointer to
+			jen.Id("typ").Op(":=").Qual("reflect", "TypeFor").Types(jen.Id("T")).Call(),
+			jen.If(jen.Id("typ").Dot("Kind").Call().Op("!=").Qual("reflect", "Pointer")).Block(
+				jen.Return(jen.Id("zero"), jen.Qual("fmt", "Errorf").Call(jen.Lit("No constructor found for %v"), jen.Id("typ"))),
+			),
+			jen.List(jen.Id("constructor"), jen.Id("ok")).Op(":=").Qual(runtimePackage, "LookupConstructor").Call(
+				jen.Id("typ").Dot("Elem").Call(),
+			),
+			jen.If(jen.Op("!").Id("ok")).Block(
+				jen.Return(jen.Id("zero"), jen.Qual("fmt", "Errorf").Call(jen.Lit("No constructor found for %v"), jen.Id("typ"))),
+			),
+			jen.List(jen.Id("typed"), jen.Err()).Op(":=").Id("constructor").Call(jen.Id("node")),
+			jen.If(jen.Err().Op("!=").Nil()).Block(
+				jen.Return(jen.Id("zero"), jen.Err()),
+			),
+			jen.Return(jen.Id("typed").Assert(jen.Id("T")), jen.Nil()),
+		)
+
+	writeDocComment(file, mustCastName+" is like "+castName+", but panics if the node can't be represented by T.")
+	file.Func().Id(mustCastName).Types(jen.Id("T").Id(typedNodeName)).
+		Params(jen.Id("node").Add(nodeType.Clone())).
+		Id("T").
+		Block(
+			jen.List(jen.Id("typed"), jen.Err()).Op(":=").Id(castName).Types(jen.Id("T")).Call(jen.Id("node")),
+			jen.If(jen.Err().Op("!=").Nil()).Block(
+				jen.Panic(jen.Err()),
+			),
+			jen.Return(jen.Id("typed")),
+		)
+}
+
+// writeWrapFunction adds the `Wrap` function, which creates the concrete typed node for
+// a node based on its kind.
+func writeWrapFunction(file *jen.File, nodeTypes nodeTypes, nm *nodeMap) {
+	namedCases := []jen.Code{}
+	unnamedCases := []jen.Code{}
+	for _, nodeType := range nodeTypes {
+		if nodeType.Subtypes != nil {
+			continue
+		}
+		structName, _ := nm.getStructName(nodeType.Type, nodeType.Named)
+		// Named and unnamed kinds can share a name, so they're kept in separate
+		// switch statements.
+		c := jen.Case(jen.Lit(nodeType.Type)).Block(
+			jen.Return(jen.Id(constructorName(structName)).Call(jen.Id("node"))),
+		)
+		if nodeType.Named {
+			namedCases = append(namedCases, c)
+		} else {
+			unnamedCases = append(unnamedCases, c)
+		}
+	}
+
+	wrapName := nm.names.get("Wrap")
+	writeDocComment(file, wrapName+" creates the concrete typed node for the given node based on its kind.")
+	file.Func().Id(wrapName).
+		Params(jen.Id("node").Op("*").Qual("github.com/tree-sitter/go-tree-sitter", "Node")).
+		Params(jen.Id(nm.names.get("TypedNode")), jen.Error()).
+		Block(
+			jen.If(jen.Id("node").Dot("IsNamed").Call()).Block(
+				jen.Switch(jen.Id("node").Dot("Kind").Call()).Block(namedCases...),
+			).Else().Block(
+				jen.Switch(jen.Id("node").Dot("Kind").Call()).Block(unnamedCases...),
+			),
+			jen.Return(jen.Nil(), jen.Qual(runtimePackage, "NewKindMismatchError").Call(jen.Nil(), jen.Id("node"))),
+		)
+}
+
+// constructorName returns the name of the 'New*' function for the given struct. Private
+// structs get a private constructor.
+func constructorName(structName string) string {
+	if unicode.IsLower(rune(structName[0])) {
+		return "new" + upperFirst(structName)
+	}
+	return "New" + upperFirst(structName)
+}
+
+// writeDocComment adds a doc comment made of the given paragraphs, wrapping lines at
+// 80 columns. Empty paragraphs are skipped.
+func writeDocComment(file *jen.File, paragraphs ...string) {
+	first := true
+	for _, paragraph := range paragraphs {
+		if paragraph == "" {
+			continue
+		}
+		if !first {
+			file.Comment("")
+		}
+		first = false
+
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			if line != "" && len(line)+len(word)+1 > 80 {
+				file.Comment(line)
+				line = ""
+			}
+			if line != "" {
+				line += " "
+			}
+			line += word
+		}
+		file.Comment(line)
+	}
+}
+
+// formatKinds formats a list of Tree-sitter kinds for a doc comment.
+func formatKinds(tsKinds []string) string {
+	quoted := []string{}
+	for _, tsKind := range tsKinds {
+		quoted = append(quoted, strconv.Quote(tsKind))
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// formatCardinality describes how many nodes a field can hold for a doc comment.
+func formatCardinality(fieldDef methodDef) string {
+	switch {
+	case fieldDef.array && fieldDef.required:
+		return "one or more"
+	case fieldDef.array:
+		return "zero or more"
+	case fieldDef.required:
+		return "exactly one"
+	default:
+		return "zero or one"
+	}
+}
+
+// accessorName returns the exported name of the generated method for a field.
+func accessorName(methodName string) string {
+	funcName := upperFirst(methodName)
+
+	// Need to make sure we don't override any of the reserved node methods,
+	// so prefix with 'Get' until the name is unique.
+	for slices.Contains(reservedNodeMethods, funcName) || strings.HasPrefix(funcName, "Append") {
+		funcName = "Get" + funcName
+	}
+	return funcName
+}
+
 func upperFirst(s string) string {
 	if len(s) == 0 {
 		return ""
@@ -587,17 +1298,26 @@ func writeStruct(file *jen.File, stDef structDef) {
 	embedField := jen.Qual("github.com/tree-sitter/go-tree-sitter", "Node")
 	structFields := []jen.Code{embedField}
 
+	writeDocComment(file, stDef.doc...)
 	file.Type().Id(stDef.name).Struct(structFields...)
 
 	structMethodIdentifier := strings.ToLower(string(stDef.name[0]))
-	for _, fieldDef := range stDef.methods {
-		funcName := upperFirst(fieldDef.methodName)
 
-		// Need to make sure we don't override any of the reserved node methods,
-		// so prefix with 'Get' until the name is unique.
-		for slices.Contains(reservedNodeMethods, funcName) {
-			funcName = "Get" + funcName
-		}
+	file.Comment("AsNode returns the underlying Tree-sitter node.")
+	file.Func().
+		Parens(jen.Id(structMethodIdentifier).Op("*").Id(stDef.name)).
+		Id("AsNode").
+		Params().
+		Op("*").Qual("github.com/tree-sitter/go-tree-sitter", "Node").
+		Block(jen.Return(jen.Op("&").Id(structMethodIdentifier).Dot("Node")))
+
+	// In compact mode, these are package-level functions instead
+	if !stDef.compact {
+		writeJSONMethods(file, stDef, structMethodIdentifier)
+	}
+
+	for _, fieldDef := range stDef.methods {
+		funcName := accessorName(fieldDef.methodName)
 
 		returnTypeStmt := jen.Null()
 		if fieldDef.array {
@@ -626,6 +1346,12 @@ func writeStruct(file *jen.File, stDef structDef) {
 				tsKindsArray = append(tsKindsArray, jen.Lit(tsKind))
 			}
 			tsKindsArrayLit := jen.Index().String().Values(tsKindsArray...)
+			member := jen.Return(
+				jen.Op("&").Id(fieldDef.returnType).Values(jen.Dict{
+					jen.Id("Node"): jen.Id(structMethodIdentifier).Dot("Node"),
+				}),
+				jen.Nil(),
+			)
 			functionBody = []jen.Code{
 				jen.Id(tsKindsVarName).Op(":=").Add(tsKindsArrayLit),
 				jen.If(
@@ -643,20 +1369,26 @@ func writeStruct(file *jen.File, stDef structDef) {
 						Block(
 							jen.Return(
 								jen.Nil(),
-								jen.Qual("fmt", "Errorf").Call(
-									jen.Lit("Node is a %s, not in %v"),
-									jen.Id(structMethodIdentifier).Dot("Node").Dot("Kind").Call(),
+								jen.Qual(runtimePackage, "NewKindMismatchError").Call(
 									jen.Id(tsKindsVarName),
+									jen.Op("&").Id(structMethodIdentifier).Dot("Node"),
 								),
 							),
 						),
 				),
-				jen.Return(
-					jen.Op("&").Id(fieldDef.returnType).Values(jen.Dict{
-						jen.Id("Node"): jen.Id(structMethodIdentifier).Dot("Node"),
-					}),
-					jen.Nil(),
-				),
+				member,
+			}
+			if stDef.resolvedIDs {
+				functionBody = []jen.Code{
+					kindIDSwitch(jen.Id(structMethodIdentifier).Dot("Node"), fieldDef.structNames, member),
+					jen.Return(
+						jen.Nil(),
+						jen.Qual(runtimePackage, "NewKindMismatchError").Call(
+							tsKindsArrayLit,
+							jen.Op("&").Id(structMethodIdentifier).Dot("Node"),
+						),
+					),
+				}
 			}
 		} else if fieldDef.array {
 			// Take in a tree cursor for iterating
@@ -667,16 +1399,22 @@ func writeStruct(file *jen.File, stDef structDef) {
 			pluralVarName := "children"
 			outputVarName := "output"
 
+			childrenStmt := jen.Id(structMethodIdentifier).
+				Dot("Node").
+				Dot("ChildrenByFieldName").
+				Call(
+					jen.Lit(fieldDef.tsFieldName),
+					jen.Id(cursorVarName),
+				)
+			if stDef.resolvedIDs {
+				childrenStmt = jen.Qual(runtimePackage, "ChildrenByFieldID").Call(
+					jen.Op("&").Id(structMethodIdentifier).Dot("Node"),
+					jen.Id(fieldIDVarName(fieldDef.tsFieldName)),
+					jen.Id(cursorVarName),
+				)
+			}
 			functionBody = []jen.Code{
-				jen.Id(pluralVarName).
-					Op(":=").
-					Id(structMethodIdentifier).
-					Dot("Node").
-					Dot("ChildrenByFieldName").
-					Call(
-						jen.Lit(fieldDef.tsFieldName),
-						jen.Id(cursorVarName),
-					),
+				jen.Id(pluralVarName).Op(":=").Add(childrenStmt),
 				jen.Id(outputVarName).Op(":=").Index().Op("*").Id(fieldDef.returnType).Values(),
 				jen.For(
 					jen.List(jen.Id("_"), jen.Id(singularVarName)).
@@ -697,13 +1435,18 @@ func writeStruct(file *jen.File, stDef structDef) {
 		} else {
 			varName := "child"
 
-			functionBody = []jen.Code{
-				jen.Id(varName).
-					Op(":=").
-					Id(structMethodIdentifier).
+			childStmt := jen.Id(structMethodIdentifier).
+				Dot("Node").
+				Dot("ChildByFieldName").
+				Call(jen.Lit(fieldDef.tsFieldName))
+			if stDef.resolvedIDs {
+				childStmt = jen.Id(structMethodIdentifier).
 					Dot("Node").
-					Dot("ChildByFieldName").
-					Call(jen.Lit(fieldDef.tsFieldName)),
+					Dot("ChildByFieldId").
+					Call(jen.Id(fieldIDVarName(fieldDef.tsFieldName)))
+			}
+			functionBody = []jen.Code{
+				jen.Id(varName).Op(":=").Add(childStmt),
 				jen.
 					If(
 						jen.Id(varName).Op("==").Nil(),
@@ -711,9 +1454,8 @@ func writeStruct(file *jen.File, stDef structDef) {
 					Block(
 						jen.Return(
 							jen.Nil(),
-							jen.Qual("fmt", "Errorf").Call(
-								jen.Lit("Node of kind %s has no "+varName+" of name %s"),
-								jen.Lit(stDef.tsKind),
+							jen.Qual(runtimePackage, "NewMissingFieldError").Call(
+								jen.Op("&").Id(structMethodIdentifier).Dot("Node"),
 								jen.Lit(fieldDef.tsFieldName),
 							),
 						),
@@ -727,6 +1469,26 @@ func writeStruct(file *jen.File, stDef structDef) {
 			}
 		}
 
+		if stDef.compact {
+			functionBody = []jen.Code{jen.Return(compactAccessorCall(stDef, fieldDef, structMethodIdentifier))}
+		}
+
+		if stDef.isUnionType {
+			writeDocComment(file, fmt.Sprintf(
+				"%s returns the node as a %s, returning an error if it isn't of kind %s.",
+				funcName,
+				fieldDef.returnType,
+				formatKinds(fieldDef.tsKinds),
+			))
+		} else {
+			writeDocComment(
+				file,
+				fmt.Sprintf("%s returns the %q field.", funcName, fieldDef.tsFieldName),
+				fmt.Sprintf("Cardinality: %s. Kinds: %s.", formatCardinality(fieldDef), formatKinds(fieldDef.tsKinds)),
+				fieldDef.description,
+			)
+		}
+
 		stmt := jen.Func().
 			Parens(
 				jen.Id(structMethodIdentifier).Op("*").Id(stDef.name),
@@ -739,6 +1501,18 @@ func writeStruct(file *jen.File, stDef structDef) {
 		file.Add(stmt)
 	}
 
+	if stDef.extrasReturnType != "" && !stDef.compact {
+		writeExtrasMethods(file, stDef, structMethodIdentifier)
+	}
+
+	if stDef.allChildrenReturnType != "" && !stDef.compact {
+		writeAllChildrenMethod(file, stDef, structMethodIdentifier)
+	}
+
+	if stDef.appendMethods {
+		writeAppendMethods(file, stDef, structMethodIdentifier)
+	}
+
 	if stDef.childrenMethodDef == nil {
 		return
 	}
@@ -768,33 +1542,29 @@ func writeStruct(file *jen.File, stDef structDef) {
 	functionParams = jen.Id(cursorVarName).Op("*").Qual("github.com/tree-sitter/go-tree-sitter", "TreeCursor")
 
 	singularVarName := "child"
-	pluralVarName := "children"
 	outputVarName := "output"
 
 	functionBody = []jen.Code{
-		jen.Id(pluralVarName).
-			Op(":=").
-			Id(structMethodIdentifier).
-			Dot("Node").
-			Dot("Children").
-			Call(jen.Id(cursorVarName)),
 		jen.Id(outputVarName).Op(":=").Index().Id(stDef.childrenMethodDef.returnType).Values(),
+		jen.Id(cursorVarName).Dot("Reset").Call(jen.Id(structMethodIdentifier).Dot("Node")),
 		jen.For(
-			jen.List(jen.Id("_"), jen.Id(singularVarName)).
-				Op(":=").
-				Range().
-				Id(pluralVarName),
-		).
-			Block(
-				jen.If(jen.Id(singularVarName).Dot("IsNamed").Call()).Block(
-					jen.Id(outputVarName).Op("=").Append(
-						jen.Id(outputVarName),
-						jen.Id(stDef.childrenMethodDef.returnType).Values(jen.Dict{
-							jen.Id("Node"): jen.Id(singularVarName),
-						}),
-					),
+			jen.Id("ok").Op(":=").Id(cursorVarName).Dot("GotoFirstChild").Call(),
+			jen.Id("ok"),
+			jen.Id("ok").Op("=").Id(cursorVarName).Dot("GotoNextSibling").Call(),
+		).Block(
+			// Extras are accessed through `LeadingExtras` and `TrailingExtras`
+			jen.Id(singularVarName).Op(":=").Id(cursorVarName).Dot("Node").Call(),
+			jen.If(
+				jen.Id(singularVarName).Dot("IsNamed").Call().Op("&&").Op("!").Id(singularVarName).Dot("IsExtra").Call(),
+			).Block(
+				jen.Id(outputVarName).Op("=").Append(
+					jen.Id(outputVarName),
+					jen.Id(stDef.childrenMethodDef.returnType).Values(jen.Dict{
+						jen.Id("Node"): jen.Op("*").Id(singularVarName),
+					}),
 				),
 			),
+		),
 	}
 
 	if stDef.childrenMethodDef.array {
@@ -805,9 +1575,8 @@ func writeStruct(file *jen.File, stDef structDef) {
 				Block(
 					jen.Return(
 						jen.Id(stDef.childrenMethodDef.returnType).Values(),
-						jen.Qual("fmt", "Errorf").Call(
-							jen.Lit("No children found on node of kind %s"),
-							jen.Lit(stDef.tsKind),
+						jen.Qual(runtimePackage, "NewMissingChildError").Call(
+							jen.Op("&").Id(structMethodIdentifier).Dot("Node"),
 						),
 					),
 				),
@@ -815,6 +1584,33 @@ func writeStruct(file *jen.File, stDef structDef) {
 		}...)
 	}
 
+	if stDef.compact {
+		helper := "Children"
+		if !stDef.childrenMethodDef.array {
+			helper = "Child"
+		}
+		functionBody = []jen.Code{jen.Return(
+			jen.Qual(runtimePackage, helper).
+				Types(jen.Id(stDef.childrenMethodDef.returnType)).
+				Call(jen.Op("&").Id(structMethodIdentifier).Dot("Node"), jen.Id(cursorVarName)),
+		)}
+	}
+
+	childrenDoc := "TypedChildren returns the named children of the node, including the ones in a field. " +
+		"Extras are returned by `LeadingExtras` and `TrailingExtras` instead."
+	if !stDef.childrenMethodDef.array {
+		childrenDoc = "TypedChild returns the first named child of the node, including the ones in a field. " +
+			"Extras are returned by `LeadingExtras` and `TrailingExtras` instead."
+	}
+	writeDocComment(
+		file,
+		childrenDoc,
+		fmt.Sprintf(
+			"Cardinality: %s. Kinds: %s.",
+			formatCardinality(*stDef.childrenMethodDef),
+			formatKinds(stDef.childrenMethodDef.tsKinds),
+		),
+	)
 	file.Func().
 		Parens(
 			jen.Id(structMethodIdentifier).Op("*").Id(stDef.name),
@@ -825,6 +1621,217 @@ func writeStruct(file *jen.File, stDef structDef) {
 		Block(functionBody...)
 }
 
+// writeJSONMethods adds `ToJSONNode` and `MarshalJSON` methods to the struct. Fields
+// and children are converted recursively through their typed accessors. Union types
+// wrap their node in its concrete type first, so that its fields are included.
+func writeJSONMethods(file *jen.File, stDef structDef, structMethodIdentifier string) {
+	sourceVarName := "source"
+	outputVarName := "output"
+	cursorVarName := "cursor"
+	jsonNodeType := jen.Op("*").Qual(runtimePackage, "JSONNode")
+
+	var functionBody []jen.Code
+	if stDef.isUnionType {
+		functionBody = []jen.Code{
+			jen.List(jen.Id("typed"), jen.Err()).Op(":=").Id(stDef.wrapName).Call(jen.Op("&").Id(structMethodIdentifier).Dot("Node")),
+			jen.If(jen.Err().Op("!=").Nil()).Block(
+				jen.Return(jen.Qual(runtimePackage, "NewJSONNode").Call(jen.Op("&").Id(structMethodIdentifier).Dot("Node"), jen.Id(sourceVarName))),
+			),
+			jen.Return(jen.Id("typed").Dot("ToJSONNode").Call(jen.Id(sourceVarName))),
+		}
+	} else {
+		functionBody = []jen.Code{
+			jen.Id(outputVarName).Op(":=").Qual(runtimePackage, "NewJSONNode").Call(jen.Op("&").Id(structMethodIdentifier).Dot("Node"), jen.Id(sourceVarName)),
+		}
+
+		needsCursor := stDef.childrenMethodDef != nil
+		for _, fieldDef := range stDef.methods {
+			needsCursor = needsCursor || fieldDef.array
+		}
+		if needsCursor {
+			functionBody = append(functionBody,
+				jen.Id(cursorVarName).Op(":=").Id(structMethodIdentifier).Dot("Node").Dot("Walk").Call(),
+				jen.Defer().Id(cursorVarName).Dot("Close").Call(),
+			)
+		}
+
+		for _, fieldDef := range stDef.methods {
+			funcName := accessorName(fieldDef.methodName)
+			if fieldDef.array {
+				functionBody = append(functionBody, jen.Block(
+					jen.Id("children").Op(":=").Index().Add(jsonNodeType.Clone()).Values(),
+					jen.For(
+						jen.List(jen.Id("_"), jen.Id("child")).Op(":=").Range().Id(structMethodIdentifier).Dot(funcName).Call(jen.Id(cursorVarName)),
+					).Block(
+						jen.Id("children").Op("=").Append(jen.Id("children"), jen.Id("child").Dot("ToJSONNode").Call(jen.Id(sourceVarName))),
+					),
+					jen.Id(outputVarName).Dot("Fields").Index(jen.Lit(fieldDef.tsFieldName)).Op("=").Id("children"),
+				))
+				continue
+			}
+			functionBody = append(functionBody,
+				jen.If(
+					jen.List(jen.Id("child"), jen.Err()).Op(":=").Id(structMethodIdentifier).Dot(funcName).Call(),
+					jen.Err().Op("==").Nil(),
+				).Block(
+					jen.Id(outputVarName).Dot("Fields").Index(jen.Lit(fieldDef.tsFieldName)).Op("=").Id("child").Dot("ToJSONNode").Call(jen.Id(sourceVarName)),
+				),
+			)
+		}
+
+		if stDef.childrenMethodDef != nil {
+			loopBody := []jen.Code{
+				// Children in a field are already in the fields, and extras aren't
+				// included. The field is checked first, as it doesn't need the child node.
+				jen.If(jen.Id(cursorVarName).Dot("FieldId").Call().Op("!=").Lit(0)).Block(jen.Continue()),
+				jen.Id("child").Op(":=").Id(cursorVarName).Dot("Node").Call(),
+				jen.If(jen.Op("!").Id("child").Dot("IsNamed").Call().Op("||").Id("child").Dot("IsExtra").Call()).Block(jen.Continue()),
+				jen.Id(outputVarName).Dot("Children").Op("=").Append(
+					jen.Id(outputVarName).Dot("Children"),
+					jen.Parens(jen.Op("&").Id(stDef.childrenMethodDef.returnType).Values(jen.Dict{
+						jen.Id("Node"): jen.Op("*").Id("child"),
+					})).Dot("ToJSONNode").Call(jen.Id(sourceVarName)),
+				),
+			}
+			if !stDef.childrenMethodDef.array {
+				loopBody = append(loopBody, jen.Break())
+			}
+			functionBody = append(functionBody,
+				jen.Id(cursorVarName).Dot("Reset").Call(jen.Id(structMethodIdentifier).Dot("Node")),
+				jen.For(
+					jen.Id("ok").Op(":=").Id(cursorVarName).Dot("GotoFirstChild").Call(),
+					jen.Id("ok"),
+					jen.Id("ok").Op("=").Id(cursorVarName).Dot("GotoNextSibling").Call(),
+				).Block(loopBody...),
+			)
+		}
+
+		functionBody = append(functionBody, jen.Return(jen.Id(outputVarName)))
+	}
+
+	file.Comment("ToJSONNode converts the node and its fields and children into their JSON")
+	file.Comment("representation. If source is non-nil, leaf nodes include their text.")
+	file.Func().
+		Parens(jen.Id(structMethodIdentifier).Op("*").Id(stDef.name)).
+		Id("ToJSONNode").
+		Params(jen.Id(sourceVarName).Index().Byte()).
+		Add(jsonNodeType.Clone()).
+		Block(functionBody...)
+
+	file.Comment("MarshalJSON converts the node into its JSON representation, without any source text.")
+	file.Func().
+		Parens(jen.Id(structMethodIdentifier).Op("*").Id(stDef.name)).
+		Id("MarshalJSON").
+		Params().
+		Params(jen.Index().Byte(), jen.Error()).
+		Block(
+			jen.Return(jen.Qual("encoding/json", "Marshal").Call(jen.Id(structMethodIdentifier).Dot("ToJSONNode").Call(jen.Nil()))),
+		)
+}
+
+// writeEqualFunctions adds the `Equal` and `Hash` functions, which compare the subtrees
+// of typed nodes by kind, field and token text. They're functions rather than methods,
+// so that they don't take the names of `equal` and `hash` fields.
+func writeEqualFunctions(file *jen.File, nm *nodeMap) {
+	typedNodeName := nm.names.get("TypedNode")
+	equalName := nm.names.get("Equal")
+	hashName := nm.names.get("Hash")
+	optionsType := jen.Qual(runtimePackage, "EqualOptions")
+
+	writeDocComment(
+		file,
+		equalName+" reports whether the nodes have the same kinds, fields and token text. The options control which differences are ignored, such as comments or identifier names.",
+	)
+	file.Func().Id(equalName).
+		Params(
+			jen.List(jen.Id("node"), jen.Id("other")).Id(typedNodeName),
+			jen.List(jen.Id("source"), jen.Id("otherSource")).Index().Byte(),
+			jen.Id("options").Add(optionsType.Clone()),
+		).
+		Bool().
+		Block(jen.Return(jen.Qual(runtimePackage, "EqualNodes").Call(
+			jen.Id("node").Dot("AsNode").Call(),
+			jen.Id("other").Dot("AsNode").Call(),
+			jen.Id("source"),
+			jen.Id("otherSource"),
+			jen.Id("options"),
+		)))
+
+	writeDocComment(
+		file,
+		hashName+" returns a hash of the node's kinds, fields and token text. Nodes that are equal with the same options have the same hash.",
+	)
+	file.Func().Id(hashName).
+		Params(jen.Id("node").Id(typedNodeName), jen.Id("source").Index().Byte(), jen.Id("options").Add(optionsType.Clone())).
+		Uint64().
+		Block(jen.Return(jen.Qual(runtimePackage, "HashNode").Call(jen.Id("node").Dot("AsNode").Call(), jen.Id("source"), jen.Id("options"))))
+}
+
+// writeAllChildrenMethod adds an `AllChildren` method to the struct, which returns
+// every child of the node, named or not, as an `AnyNode`.
+func writeAllChildrenMethod(file *jen.File, stDef structDef, structMethodIdentifier string) {
+	cursorVarName := "cursor"
+	singularVarName := "child"
+	pluralVarName := "children"
+	outputVarName := "output"
+
+	file.Comment("AllChildren returns every child of the node, including unnamed tokens such as")
+	file.Comment("operators and keywords, and extras such as comments.")
+	signature := file.Func().
+		Parens(jen.Id(structMethodIdentifier).Op("*").Id(stDef.name)).
+		Id("AllChildren").
+		Params(jen.Id(cursorVarName).Op("*").Qual("github.com/tree-sitter/go-tree-sitter", "TreeCursor")).
+		Index().Id(stDef.allChildrenReturnType)
+	signature.
+		Block(
+			jen.Id(pluralVarName).
+				Op(":=").
+				Id(structMethodIdentifier).
+				Dot("Node").
+				Dot("Children").
+				Call(jen.Id(cursorVarName)),
+			jen.Id(outputVarName).Op(":=").Make(jen.Index().Id(stDef.allChildrenReturnType), jen.Lit(0), jen.Len(jen.Id(pluralVarName))),
+			jen.For(
+				jen.List(jen.Id("_"), jen.Id(singularVarName)).
+					Op(":=").
+					Range().
+					Id(pluralVarName),
+			).Block(
+				jen.Id(outputVarName).Op("=").Append(
+					jen.Id(outputVarName),
+					jen.Id(stDef.allChildrenReturnType).Values(jen.Dict{
+						jen.Id("Node"): jen.Id(singularVarName),
+					}),
+				),
+			),
+			jen.Return(jen.Id(outputVarName)),
+		)
+}
+
+// writeExtrasMethods adds `LeadingExtras` and `TrailingExtras` methods to the struct,
+// which return the extra nodes (e.g. comments) directly before and after the node.
+func writeExtrasMethods(file *jen.File, stDef structDef, structMethodIdentifier string) {
+	for _, methodName := range []string{"LeadingExtras", "TrailingExtras"} {
+		if methodName == "LeadingExtras" {
+			file.Comment("LeadingExtras returns the extra nodes, such as comments, directly preceding the node.")
+			file.Comment("Extras on the line the previous node ends on are its trailing extras instead.")
+		} else {
+			file.Comment("TrailingExtras returns the extra nodes, such as comments, directly following the node")
+			file.Comment("on the line it ends on, or all of them if it's the last node.")
+		}
+		file.Func().
+			Parens(jen.Id(structMethodIdentifier).Op("*").Id(stDef.name)).
+			Id(methodName).
+			Params().
+			Index().Op("*").Id(stDef.extrasReturnType).
+			Block(jen.Return(
+				jen.Qual(runtimePackage, methodName+"Of").
+					Types(jen.Id(stDef.extrasReturnType)).
+					Call(jen.Op("&").Id(structMethodIdentifier).Dot("Node")),
+			))
+	}
+}
+
 // createExportedName creates a name that is safe to use as an exported symbol name
 // from a snake_case name. it also replaces sybols with word representations of
 // those symbols.
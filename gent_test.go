@@ -1,21 +1,57 @@
 package gent_test
 
 import (
+	"bytes"
 	_ "embed"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/isaacharrisholt/gent"
+	"github.com/isaacharrisholt/gent/runtime"
 	python "github.com/isaacharrisholt/gent/testdata"
+	"github.com/isaacharrisholt/gent/tool"
 	tree_sitter "github.com/tree-sitter/go-tree-sitter"
 	tree_sitter_python "github.com/tree-sitter/tree-sitter-python/bindings/go"
 )
 
+//go:generate go run ./cmd/gent generate -p python -l github.com/tree-sitter/tree-sitter-python/bindings/go --performance -c testdata/python-config.json -o testdata/python.go testdata/python-node-types.json
+
 //go:embed testdata/python-node-types.json
 var pythonNodeTypes []byte
 
 //go:embed testdata/test_program.py
 var testPythonProgram []byte
 
+func TestGenerator_GeneratePythonFixture(t *testing.T) {
+	config, err := gent.LoadConfig("testdata/python-config.json")
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	output, err := gent.NewGenerator(gent.GeneratorOptions{
+		PackageName: "python",
+		Config:      config,
+		Language:    "github.com/tree-sitter/tree-sitter-python/bindings/go",
+		Performance: true,
+	}).Generate(pythonNodeTypes)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	fixture, err := os.ReadFile("testdata/python.go")
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+	if output != string(fixture) {
+		t.Fatalf("testdata/python.go is out of date, run `go generate`")
+	}
+}
+
 func TestGenerator_Generate(t *testing.T) {
 	gen := gent.NewGenerator(gent.GeneratorOptions{
 		PackageName: "python_nodes",
@@ -73,3 +109,1782 @@ func TestPythonParse(t *testing.T) {
 		t.Fatalf("Expected import statement name to be sys, got %s", name)
 	}
 }
+
+// parseTestPythonProgram parses the test Python program, returning the typed module
+// and a cursor that can be used for iterating. Both are closed when the test ends.
+func parseTestPythonProgram(t *testing.T) (*python.Module, *tree_sitter.TreeCursor) {
+	t.Helper()
+
+	tree, module, err := python.Parse(testPythonProgram)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	t.Cleanup(tree.Close)
+
+	cursor := tree.Walk()
+	t.Cleanup(cursor.Close)
+
+	return module, cursor
+}
+
+// extrasText returns the text of each extra.
+func extrasText[T interface{ Utf8Text([]byte) string }](extras []T, source []byte) []string {
+	texts := []string{}
+	for _, extra := range extras {
+		texts = append(texts, extra.Utf8Text(source))
+	}
+	return texts
+}
+
+func TestPythonExtras(t *testing.T) {
+	module, cursor := parseTestPythonProgram(t)
+
+	// Comments shouldn't be returned as typed children
+	topLevelStatements := module.TypedChildren(cursor)
+	if len(topLevelStatements) != 3 {
+		t.Fatalf("Expected 3 top level statements, got %v", len(topLevelStatements))
+	}
+
+	functionDefinition := topLevelStatements[1]
+	if functionDefinition.Kind() != python.SyntaxKind_FunctionDefinition {
+		t.Fatalf("Expected second child to be a function definition, got %v", functionDefinition.Kind())
+	}
+
+	leadingExtras := functionDefinition.LeadingExtras()
+	if len(leadingExtras) != 1 {
+		t.Fatalf("Expected 1 leading extra, got %v", len(leadingExtras))
+	}
+
+	comment, err := leadingExtras[0].Comment()
+	if err != nil {
+		t.Fatalf("Failed to get comment: %v", err)
+	}
+	if text := getPythonNodeText(&comment.Node); text != "# Entry point" {
+		t.Fatalf("Expected comment to be '# Entry point', got %s", text)
+	}
+
+	if trailingExtras := functionDefinition.TrailingExtras(); len(trailingExtras) != 0 {
+		t.Fatalf("Expected no trailing extras, got %v", len(trailingExtras))
+	}
+	// The comment belongs to the function definition, not the import before it
+	if trailingExtras := topLevelStatements[0].TrailingExtras(); len(trailingExtras) != 0 {
+		t.Fatalf("Expected the import to have no trailing extras, got %v", len(trailingExtras))
+	}
+
+	// Comments on the line a statement ends on are its trailing extras, and others
+	// lead the next statement
+	source := []byte("x = 1  # one\n# two\ny = 2\n# three\n")
+	tree, other, err := python.Parse(source)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	defer tree.Close()
+	statements := other.TypedChildren(cursor)
+	for _, tt := range []struct {
+		name     string
+		actual   []string
+		expected []string
+	}{
+		{"x leading", extrasText(statements[0].LeadingExtras(), source), []string{}},
+		{"x trailing", extrasText(statements[0].TrailingExtras(), source), []string{"# one"}},
+		{"y leading", extrasText(statements[1].LeadingExtras(), source), []string{"# two"}},
+		{"y trailing", extrasText(statements[1].TrailingExtras(), source), []string{"# three"}},
+	} {
+		if !slices.Equal(tt.actual, tt.expected) {
+			t.Errorf("Expected %s extras %v, got %v", tt.name, tt.expected, tt.actual)
+		}
+	}
+}
+
+func TestPythonAllChildren(t *testing.T) {
+	module, cursor := parseTestPythonProgram(t)
+	topLevelStatements := module.TypedChildren(cursor)
+
+	ifStatement, err := python.NewIfStatement(&topLevelStatements[2].Node)
+	if err != nil {
+		t.Fatalf("Failed to create if statement: %v", err)
+	}
+	condition, err := ifStatement.Condition()
+	if err != nil {
+		t.Fatalf("Failed to get condition: %v", err)
+	}
+	comparison, err := python.NewComparisonOperator(&condition.Node)
+	if err != nil {
+		t.Fatalf("Failed to create comparison operator: %v", err)
+	}
+
+	// The `==` token is unnamed, so it's only reachable through `AllChildren`
+	children := comparison.AllChildren(cursor)
+	if len(children) != 3 {
+		t.Fatalf("Expected 3 children, got %v", len(children))
+	}
+
+	operator, err := children[1].Unnamed_EqEq()
+	if err != nil {
+		t.Fatalf("Failed to get == operator: %v", err)
+	}
+	if text := getPythonNodeText(&operator.Node); text != "==" {
+		t.Fatalf("Expected operator to be ==, got %s", text)
+	}
+
+	if _, err := children[0].Identifier(); err != nil {
+		t.Fatalf("Failed to get identifier: %v", err)
+	}
+}
+
+func TestPythonCast(t *testing.T) {
+	module, cursor := parseTestPythonProgram(t)
+	topLevelStatements := module.TypedChildren(cursor)
+
+	ifStatement := python.MustCast[*python.IfStatement](&topLevelStatements[2].Node)
+	condition := ifStatement.ChildByFieldName("condition")
+
+	// Supertype constructors accept any of their subtypes, recursively
+	expression, err := python.NewExpression(condition)
+	if err != nil {
+		t.Fatalf("Failed to create expression: %v", err)
+	}
+	if expression.Kind() != python.SyntaxKind_ComparisonOperator {
+		t.Fatalf("Expected expression to be a comparison operator, got %v", expression.Kind())
+	}
+	if _, err := python.NewPrimaryExpression(condition); err == nil {
+		t.Fatalf("Expected comparison operator not to be a primary expression")
+	}
+
+	comparison, err := python.Cast[*python.ComparisonOperator](condition)
+	if err != nil {
+		t.Fatalf("Failed to cast to comparison operator: %v", err)
+	}
+	if comparison.AsNode().Id() != condition.Id() {
+		t.Fatalf("Expected cast node to wrap the condition node")
+	}
+
+	if _, err := python.Cast[*python.Identifier](condition); err == nil {
+		t.Fatalf("Expected cast of comparison operator to identifier to fail")
+	}
+}
+
+func TestPythonErrors(t *testing.T) {
+	module, cursor := parseTestPythonProgram(t)
+	topLevelStatements := module.TypedChildren(cursor)
+
+	_, err := python.NewIdentifier(&topLevelStatements[2].Node)
+	var kindMismatchErr *runtime.KindMismatchError
+	if !errors.As(err, &kindMismatchErr) {
+		t.Fatalf("Expected a kind mismatch error, got %v", err)
+	}
+	if kindMismatchErr.Actual != python.SyntaxKind_IfStatement {
+		t.Fatalf("Expected actual kind to be an if statement, got %v", kindMismatchErr.Actual)
+	}
+	if !slices.Equal(kindMismatchErr.Expected, []python.SyntaxKind{python.SyntaxKind_Identifier}) {
+		t.Fatalf("Expected expected kinds to be [identifier], got %v", kindMismatchErr.Expected)
+	}
+	if kindMismatchErr.Range.StartPoint.Row != 7 {
+		t.Fatalf("Expected error to be on row 7, got %v", kindMismatchErr.Range.StartPoint.Row)
+	}
+
+	// `main` has no return type annotation
+	functionDefinition := python.MustCast[*python.FunctionDefinition](&topLevelStatements[1].Node)
+	_, err = functionDefinition.ReturnType()
+	var missingFieldErr *runtime.MissingFieldError
+	if !errors.As(err, &missingFieldErr) {
+		t.Fatalf("Expected a missing field error, got %v", err)
+	}
+	if missingFieldErr.Parent != python.SyntaxKind_FunctionDefinition || missingFieldErr.Field != "return_type" {
+		t.Fatalf("Unexpected missing field error: %v", missingFieldErr)
+	}
+}
+
+func TestPythonJSON(t *testing.T) {
+	module, cursor := parseTestPythonProgram(t)
+	topLevelStatements := module.TypedChildren(cursor)
+
+	functionDefinition := python.MustCast[*python.FunctionDefinition](&topLevelStatements[1].Node)
+	jsonNode := functionDefinition.ToJSONNode(testPythonProgram)
+	if jsonNode.Kind != python.SyntaxKind_FunctionDefinition {
+		t.Fatalf("Expected kind to be function_definition, got %v", jsonNode.Kind)
+	}
+
+	name, ok := jsonNode.Fields["name"].(*runtime.JSONNode)
+	if !ok {
+		t.Fatalf("Expected name field to be a single node, got %T", jsonNode.Fields["name"])
+	}
+	if name.Text == nil || *name.Text != "main" {
+		t.Fatalf("Expected name text to be main, got %v", name.Text)
+	}
+	if _, ok := jsonNode.Fields["return_type"]; ok {
+		t.Fatalf("Expected missing return type to be omitted")
+	}
+
+	// Union types are converted using their concrete kind
+	output, err := json.Marshal(&topLevelStatements[1])
+	if err != nil {
+		t.Fatalf("Failed to marshal function definition: %v", err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(output, &decoded); err != nil {
+		t.Fatalf("Failed to unmarshal function definition: %v", err)
+	}
+	if decoded["kind"] != "function_definition" {
+		t.Fatalf("Expected kind to be function_definition, got %v", decoded["kind"])
+	}
+	fields, ok := decoded["fields"].(map[string]any)
+	if !ok || fields["body"] == nil {
+		t.Fatalf("Expected body field in %s", output)
+	}
+	if _, ok := fields["name"].(map[string]any)["text"]; ok {
+		t.Fatalf("Expected no text when marshalling without source")
+	}
+}
+
+func TestGenerator_GenerateJSONSchema(t *testing.T) {
+	gen := gent.NewGenerator(gent.GeneratorOptions{})
+	output, err := gen.GenerateJSONSchema(pythonNodeTypes)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var schema struct {
+		Ref  string                    `json:"$ref"`
+		Defs map[string]map[string]any `json:"$defs"`
+	}
+	if err := json.Unmarshal([]byte(output), &schema); err != nil {
+		t.Fatalf("Failed to unmarshal schema: %v", err)
+	}
+	if schema.Ref != "#/$defs/Module" {
+		t.Fatalf("Expected schema to reference the root module, got %v", schema.Ref)
+	}
+	for _, name := range []string{"FunctionDefinition", "Expression", "Unnamed_Add", "Gent_Range"} {
+		if _, ok := schema.Defs[name]; !ok {
+			t.Fatalf("Expected schema to define %s", name)
+		}
+	}
+	if _, ok := schema.Defs["Expression"]["anyOf"]; !ok {
+		t.Fatalf("Expected supertype schema to be a union")
+	}
+}
+
+func TestGenerator_GenerateFormats(t *testing.T) {
+	tests := []struct {
+		format   gent.Format
+		expected []string
+	}{
+		{
+			format: gent.FormatProtobuf,
+			expected: []string{
+				"package python_nodes;",
+				"message FunctionDefinition {",
+				"  Identifier name = 4;",
+				"message Expression {\n  oneof node {",
+			},
+		},
+		{
+			format: gent.FormatTypeScript,
+			expected: []string{
+				"export interface FunctionDefinition {\n  kind: \"function_definition\";",
+				"    return_type?: Type;",
+				"export type Expression = ",
+			},
+		},
+		{
+			format: gent.FormatMarkdown,
+			expected: []string{
+				"| `name` | `Name() (*Identifier, error)` | exactly one | [`Identifier`](#identifier) |  |",
+				"Member of: [`CompoundStatement`](#compoundstatement)",
+				"Used in: [`DecoratedDefinition`](#decorateddefinition).`definition`",
+			},
+		},
+		{
+			format: gent.FormatHTML,
+			expected: []string{
+				`<section id="classdefinition">`,
+				`<a href="#decorateddefinition"><code>DecoratedDefinition</code></a>.<code>definition</code>`,
+			},
+		},
+		{
+			format:   gent.FormatJSONSchema,
+			expected: []string{`"$ref": "#/$defs/Module"`},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.format), func(t *testing.T) {
+			gen := gent.NewGenerator(gent.GeneratorOptions{
+				PackageName: "python_nodes",
+				Format:      test.format,
+			})
+			output, err := gen.Generate(pythonNodeTypes)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for _, expected := range test.expected {
+				if !strings.Contains(output, expected) {
+					t.Errorf("Expected output to contain %q", expected)
+				}
+			}
+		})
+	}
+
+	gen := gent.NewGenerator(gent.GeneratorOptions{Format: "cobol"})
+	if _, err := gen.Generate(pythonNodeTypes); err == nil {
+		t.Errorf("Expected an error for an unknown format")
+	}
+}
+
+func TestGenerator_GenerateGraph(t *testing.T) {
+	gen := gent.NewGenerator(gent.GeneratorOptions{
+		Format: gent.FormatDOT,
+		Graph: gent.GraphOptions{
+			Root:  "function_definition",
+			Depth: 1,
+		},
+	})
+	output, err := gen.Generate(pythonNodeTypes)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, `"FunctionDefinition" -> "Identifier" [label="name"];`) {
+		t.Errorf("Expected graph to contain the name field edge")
+	}
+	// Block is one edge away, so its children shouldn't be included
+	if strings.Contains(output, `"Block" ->`) {
+		t.Errorf("Expected graph to stop at depth 1")
+	}
+
+	gen = gent.NewGenerator(gent.GeneratorOptions{Format: gent.FormatMermaid})
+	output, err = gen.Generate(pythonNodeTypes)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "class k_Unknown__asPatternTarget unknown") {
+		t.Errorf("Expected unknown types to be highlighted")
+	}
+	if !strings.Contains(output, "k_Expression -.-> k_PrimaryExpression") {
+		t.Errorf("Expected supertype edges to be included")
+	}
+
+	gen = gent.NewGenerator(gent.GeneratorOptions{
+		Format: gent.FormatDOT,
+		Graph:  gent.GraphOptions{Root: "not_a_kind"},
+	})
+	if _, err := gen.Generate(pythonNodeTypes); err == nil {
+		t.Errorf("Expected an error for an unknown root kind")
+	}
+}
+
+func TestGenerator_GenerateDocComments(t *testing.T) {
+	gen := gent.NewGenerator(gent.GeneratorOptions{
+		PackageName: "python_nodes",
+		Config: gent.Config{
+			Descriptions: map[string]string{
+				"function_definition":      "A function defined with `def`.",
+				"function_definition.name": "The name the function is bound to.",
+			},
+		},
+	})
+	output, err := gen.Generate(pythonNodeTypes)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{
+		"// FunctionDefinition wraps a Tree-sitter node of kind \"function_definition\".\n//\n// A function defined with `def`.\ntype FunctionDefinition struct",
+		"// Name returns the \"name\" field.\n//\n// Cardinality: exactly one. Kinds: \"identifier\".\n//\n// The name the function is bound to.\nfunc (f *FunctionDefinition) Name()",
+		"// ReturnType returns the \"return_type\" field.\n//\n// Cardinality: zero or one.",
+		"// Expression is the \"expression\" supertype, which can be any of the following\n// kinds:",
+	}
+	for _, e := range expected {
+		if !strings.Contains(output, e) {
+			t.Errorf("Expected output to contain %q", e)
+		}
+	}
+}
+
+//go:embed testdata/corpus.txt
+var testCorpus []byte
+
+func TestPythonCorpus(t *testing.T) {
+	examples, err := runtime.ParseCorpus(testCorpus)
+	if err != nil {
+		t.Fatalf("Failed to parse corpus: %v", err)
+	}
+	if len(examples) != 3 {
+		t.Fatalf("Expected 3 examples, got %d", len(examples))
+	}
+	if examples[0].Name != "Function definitions" || !examples[2].HasAttribute("skip") {
+		t.Fatalf("Unexpected examples: %+v", examples)
+	}
+
+	for _, example := range examples {
+		sexp, err := runtime.ParseSExpression(example.Expected)
+		if err != nil {
+			t.Fatalf("Failed to parse expected tree of %q: %v", example.Name, err)
+		}
+		if problems := runtime.CheckSExpression(python.Grammar, sexp); len(problems) != 0 {
+			t.Errorf("Unexpected problems in expected tree of %q: %v", example.Name, problems)
+		}
+	}
+
+	results, err := runtime.RunCorpus(python.Language(), examples, python.CheckTree)
+	if err != nil {
+		t.Fatalf("Failed to run corpus: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected skipped examples to be ignored, got %d results", len(results))
+	}
+	for _, result := range results {
+		if len(result.Problems) != 0 {
+			t.Errorf("Unexpected problems in %q: %v", result.Example.Name, result.Problems)
+		}
+	}
+}
+
+func TestToolCorpus(t *testing.T) {
+	output := &bytes.Buffer{}
+	if err := tool.Corpus(output, []string{"testdata"}); err != nil {
+		t.Fatalf("Unexpected error: %v\n%s", err, output)
+	}
+	if output.String() != "No problems found in 1 corpus files\n" {
+		t.Errorf("Unexpected output: %s", output)
+	}
+
+	path := filepath.Join(t.TempDir(), "corpus.txt")
+	corpus := "===\nBad\n===\n\nx = 1\n\n---\n\n(module (expression_statement (assignment left: (integer) right: (integer))))\n"
+	if err := os.WriteFile(path, []byte(corpus), 0o644); err != nil {
+		t.Fatalf("Failed to write corpus: %v", err)
+	}
+	output.Reset()
+	if err := tool.Corpus(output, []string{path}); err == nil {
+		t.Fatalf("Expected an error, got output %s", output)
+	}
+	if !strings.Contains(output.String(), "Bad: expected tree: assignment: child of kind integer") {
+		t.Errorf("Expected a problem in the expected tree, got %s", output)
+	}
+}
+
+func TestToolCoverage(t *testing.T) {
+	output := &bytes.Buffer{}
+	if err := tool.Coverage(output, []string{"testdata"}, []string{".py"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output.String(), "function_definition") {
+		t.Errorf("Expected the report to mention function_definition, got %s", output)
+	}
+}
+
+func TestToolDiff(t *testing.T) {
+	dir := t.TempDir()
+	oldPath := filepath.Join(dir, "old.py")
+	newPath := filepath.Join(dir, "new.py")
+	if err := os.WriteFile(oldPath, []byte("x = 1\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+	if err := os.WriteFile(newPath, []byte("x = 2\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+
+	output := &bytes.Buffer{}
+	if err := tool.Diff(output, oldPath, newPath); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output.String(), "Integer") {
+		t.Errorf("Expected the diff to name the typed node, got %s", output)
+	}
+}
+
+// testBrokenFunctionDefinition is a function definition whose `Name` accessor returns
+// the wrong field, as a bug in generated code might.
+type testBrokenFunctionDefinition struct {
+	python.FunctionDefinition
+}
+
+func (f *testBrokenFunctionDefinition) Name() (*testPythonName, error) {
+	parameters, err := f.Parameters()
+	if err != nil {
+		return nil, err
+	}
+	return &testPythonName{Node: parameters.Node}, nil
+}
+
+func TestCheckTreeAccessors(t *testing.T) {
+	module, _ := parseTestPythonProgram(t)
+	runtime.Register(newTestPythonName)
+
+	problems := runtime.CheckTree(python.Grammar, &module.Node, func(node *tree_sitter.Node) (runtime.TypedNode, error) {
+		if node.Kind() == "function_definition" {
+			return &testBrokenFunctionDefinition{FunctionDefinition: python.FunctionDefinition{Node: *node}}, nil
+		}
+		return python.Wrap(node)
+	})
+	if len(problems) != 1 {
+		t.Fatalf("Expected 1 problem, got %v", problems)
+	}
+	if problems[0].Field != "name" || !strings.Contains(problems[0].Message, "Name returned a node of kind parameters") {
+		t.Errorf("Unexpected problem: %v", problems[0])
+	}
+}
+
+func TestCheckSExpression(t *testing.T) {
+	grammar, err := gent.BuildGrammar(pythonNodeTypes)
+	if err != nil {
+		t.Fatalf("Failed to build grammar: %v", err)
+	}
+
+	sexp, err := runtime.ParseSExpression(`
+		(module
+		  (function_definition
+		    name: (identifier)
+		    parameters: (identifier))) ; not allowed, and there's no body`)
+	if err != nil {
+		t.Fatalf("Failed to parse S-expression: %v", err)
+	}
+	problems := runtime.CheckSExpression(grammar, sexp)
+	if len(problems) != 2 {
+		t.Fatalf("Expected 2 problems, got %v", problems)
+	}
+	if problems[0].Field != "parameters" {
+		t.Errorf("Expected a problem with the parameters field, got %v", problems[0])
+	}
+	if problems[1].Field != "body" {
+		t.Errorf("Expected a problem with the body field, got %v", problems[1])
+	}
+}
+
+type testPythonVisitor struct {
+	identifiers []string
+}
+
+func (v *testPythonVisitor) VisitFunctionDefinition(node *python.FunctionDefinition) bool {
+	return false
+}
+
+func (v *testPythonVisitor) VisitReturnStatement(node *python.ReturnStatement) bool {
+	return true
+}
+
+func (v *testPythonVisitor) VisitIdentifier(node *python.Identifier) bool {
+	v.identifiers = append(v.identifiers, getPythonNodeText(&node.Node))
+	return true
+}
+
+func TestPythonCoverage(t *testing.T) {
+	module, _ := parseTestPythonProgram(t)
+
+	visitor := &testPythonVisitor{}
+	coverage := python.NewCoverage()
+	python.WalkCoverage(visitor, &module.Node, coverage)
+
+	// Identifiers in the function definition are skipped
+	expectedIdentifiers := []string{"sys", "__name__", "sys", "exit", "main"}
+	if !slices.Equal(visitor.identifiers, expectedIdentifiers) {
+		t.Fatalf("Expected identifiers %v, got %v", expectedIdentifiers, visitor.identifiers)
+	}
+
+	report := coverage.Report()
+	if !slices.Equal(report.Unreached, []python.SyntaxKind{python.SyntaxKind_ReturnStatement}) {
+		t.Errorf("Expected only the return statement to be unreached, got %v", report.Unreached)
+	}
+
+	counts := map[string]int{}
+	for _, entry := range slices.Concat(report.Kinds, report.Fields, report.Members) {
+		counts[entry.Name] = entry.Count
+	}
+	expectedCounts := map[string]int{
+		"function_definition":                  1,
+		"identifier":                           7,
+		"while_statement":                      0,
+		"function_definition.name":             1,
+		"function_definition.return_type":      0,
+		"call.function: attribute":             1,
+		"call.function: identifier":            2,
+		"comparison_operator.children: string": 1,
+	}
+	for name, expected := range expectedCounts {
+		if count, ok := counts[name]; !ok || count != expected {
+			t.Errorf("Expected %s to occur %d times, got %d", name, expected, count)
+		}
+	}
+	if unused := runtime.Unused(report.Kinds); !slices.Contains(unused, "while_statement") {
+		t.Errorf("Expected while_statement to be unused, got %v", unused)
+	}
+}
+
+func TestResolveModuleNodeTypes(t *testing.T) {
+	// A module requiring a grammar replaced by a local directory, so nothing is read
+	// from the module cache
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":                      "module example.com/user\n\ngo 1.22\n\nrequire example.com/grammar v0.0.0\n\nreplace example.com/grammar => ./grammar\n",
+		"grammar/go.mod":              "module example.com/grammar\n\ngo 1.22\n",
+		"grammar/src/node-types.json": "[]",
+		"grammar/bindings/go/go.go":   "package grammar\n",
+		"other/go.mod":                "module example.com/other\n\ngo 1.22\n",
+		"other/src/node-types.json":   "[]",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	// The go command's default, so go.mod is read-only
+	t.Setenv("GOFLAGS", "")
+
+	path, err := gent.ResolveModuleNodeTypes("example.com/grammar")
+	if err != nil {
+		t.Fatalf("Failed to resolve module: %v", err)
+	}
+	if path != filepath.Join(dir, "grammar", "src", "node-types.json") {
+		t.Fatalf("Expected node-types.json from the required module, got %s", path)
+	}
+
+	// Packages resolve to the module containing them
+	path, err = gent.ResolveModuleNodeTypes("example.com/grammar/bindings/go")
+	if err != nil {
+		t.Fatalf("Failed to resolve package: %v", err)
+	}
+	if path != filepath.Join(dir, "grammar", "src", "node-types.json") {
+		t.Fatalf("Expected node-types.json from the module containing the package, got %s", path)
+	}
+
+	goMod, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		t.Fatalf("Failed to read go.mod: %v", err)
+	}
+	if string(goMod) != files["go.mod"] {
+		t.Fatalf("Expected go.mod to be left alone, got %s", goMod)
+	}
+
+	// Modules go.mod doesn't require aren't looked up anywhere else
+	if _, err := gent.ResolveModuleNodeTypes("example.com/other"); err == nil {
+		t.Fatalf("Expected an error for a module go.mod doesn't require")
+	}
+}
+
+func TestPythonParseFile(t *testing.T) {
+	tree, module, err := python.ParseFile("testdata/test_program.py")
+	if err != nil {
+		t.Fatalf("Failed to parse file: %v", err)
+	}
+	defer tree.Close()
+
+	if module.Kind() != python.RootSyntaxKind {
+		t.Fatalf("Expected root node to be a module, got %v", module.Kind())
+	}
+
+	if _, _, err := python.ParseFile("testdata/does_not_exist.py"); err == nil {
+		t.Fatalf("Expected an error for a missing file")
+	}
+}
+
+func TestGenerator_GenerateLanguage(t *testing.T) {
+	gen := gent.NewGenerator(gent.GeneratorOptions{
+		PackageName: "python",
+		Language:    "github.com/tree-sitter/tree-sitter-python/bindings/go",
+	})
+	output, err := gen.Generate(pythonNodeTypes)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "func Parse(source []byte) (*tree_sitter.Tree, *Module, error)") {
+		t.Errorf("Expected Parse to return the root Module")
+	}
+
+	// Field and kind names are resolved to IDs once
+	for _, expected := range []string{
+		`boundLanguage.FieldIdForName("name")`,
+		`boundLanguage.IdForNodeKind("module", true)`,
+		"a.Node.ChildByFieldId(fieldID_Name)",
+		"node.KindId() != kindID_Module",
+		// Union constructors and accessors too
+		"switch node.KindId() {",
+		"switch p.Node.KindId() {\n\tcase kindID_Identifier:",
+		`boundLanguage.IdForNodeKind("as_pattern_target", true)`,
+		// Parsers reuse the bound language rather than creating one each time
+		"parser.SetLanguage(boundLanguage)",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q", expected)
+		}
+	}
+	if strings.Contains(output, "ChildByFieldName(") {
+		t.Errorf("Expected no field name lookups when a language is bound")
+	}
+	if strings.Contains(output, "slices.Contains(tsKinds") {
+		t.Errorf("Expected no kind name lookups when a language is bound")
+	}
+
+	// Fields missing from the bound language resolve to zero, which mustn't match
+	// children without a field
+	output, err = gent.NewGenerator(gent.GeneratorOptions{
+		PackageName: "python",
+		Language:    "github.com/tree-sitter/tree-sitter-python/bindings/go",
+		Performance: true,
+	}).Generate(pythonNodeTypes)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "id := fieldID_Name\n\tif id == 0 {\n\t\treturn buf\n\t}") {
+		t.Errorf("Expected Append methods to check for a zero field ID")
+	}
+
+	// The root kind can't be detected without the `root` flag
+	_, err = gen.Generate([]byte(`[{"type": "identifier", "named": true}]`))
+	if err == nil {
+		t.Errorf("Expected an error when no kind is marked as the root")
+	}
+}
+
+func TestPythonAppendTypedChildren(t *testing.T) {
+	module, cursor := parseTestPythonProgram(t)
+
+	expected := module.TypedChildren(cursor)
+	buf := module.AppendTypedChildren(nil)
+	if len(buf) != len(expected) {
+		t.Fatalf("Expected %d children, got %d", len(expected), len(buf))
+	}
+	for i := range buf {
+		if buf[i].Id() != expected[i].Id() {
+			t.Errorf("Expected child %d to be %v, got %v", i, expected[i].Kind(), buf[i].Kind())
+		}
+	}
+
+	// Existing elements in the buffer are kept
+	buf = module.AppendTypedChildren(buf)
+	if len(buf) != 2*len(expected) {
+		t.Fatalf("Expected %d children, got %d", 2*len(expected), len(buf))
+	}
+
+	importStatement, err := expected[0].SimpleStatement()
+	if err != nil {
+		t.Fatalf("Failed to get simple statement: %v", err)
+	}
+	statement, err := importStatement.ImportStatement()
+	if err != nil {
+		t.Fatalf("Failed to get import statement: %v", err)
+	}
+	names := statement.AppendName(nil)
+	if len(names) != 1 || getPythonNodeText(&names[0].Node) != "sys" {
+		t.Fatalf("Expected the import statement name to be sys, got %v", names)
+	}
+}
+
+func TestPythonTypedChildrenInFields(t *testing.T) {
+	source := []byte("{k: v for k in x}\n")
+	tree, module, err := python.Parse(source)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	defer tree.Close()
+	nodes, err := python.Select(&module.Node, source, python.MustCompileSelector("dictionary_comprehension"))
+	if err != nil || len(nodes) != 1 {
+		t.Fatalf("Expected a dictionary comprehension, got %d: %v", len(nodes), err)
+	}
+	comprehension := nodes[0].(*python.DictionaryComprehension)
+	cursor := comprehension.Walk()
+	defer cursor.Close()
+
+	// The pair is in the `body` field, and is returned along with the other children
+	kinds := []string{}
+	for _, child := range comprehension.TypedChildren(cursor) {
+		kinds = append(kinds, child.Kind())
+	}
+	if !slices.Equal(kinds, []string{"pair", "for_in_clause"}) {
+		t.Fatalf("Expected the pair and the for clause, got %v", kinds)
+	}
+	if appended := comprehension.AppendTypedChildren(nil); len(appended) != len(kinds) {
+		t.Fatalf("Expected %d appended children, got %d", len(kinds), len(appended))
+	}
+
+	// JSON only lists the pair in its field
+	jsonNode := comprehension.ToJSONNode(source)
+	if _, ok := jsonNode.Fields["body"]; !ok || len(jsonNode.Children) != 1 || jsonNode.Children[0].Kind != "for_in_clause" {
+		t.Fatalf("Expected the pair in the body field and the for clause as the only child, got %+v", jsonNode)
+	}
+}
+
+// parseBenchmarkPythonProgram parses the test program repeated many times, so that
+// the module has lots of children.
+func parseBenchmarkPythonProgram(b *testing.B) *python.Module {
+	b.Helper()
+
+	tree, module, err := python.Parse(bytes.Repeat(testPythonProgram, 200))
+	if err != nil {
+		b.Fatalf("Failed to parse program: %v", err)
+	}
+	b.Cleanup(tree.Close)
+	return module
+}
+
+func BenchmarkPythonTypedChildren(b *testing.B) {
+	module := parseBenchmarkPythonProgram(b)
+	cursor := module.Walk()
+	defer cursor.Close()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		module.TypedChildren(cursor)
+	}
+}
+
+func BenchmarkPythonAppendTypedChildren(b *testing.B) {
+	module := parseBenchmarkPythonProgram(b)
+	// Union types are unexported, so the buffer's type comes from the first call
+	buf := module.AppendTypedChildren(nil)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		buf = module.AppendTypedChildren(buf[:0])
+	}
+}
+
+func TestGenerator_GenerateCompact(t *testing.T) {
+	full, err := gent.NewGenerator(gent.GeneratorOptions{PackageName: "python"}).Generate(pythonNodeTypes)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	compact, err := gent.NewGenerator(gent.GeneratorOptions{PackageName: "python", Compact: true}).Generate(pythonNodeTypes)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(compact) >= len(full) {
+		t.Errorf("Expected compact output to be smaller, got %d bytes, compared to %d", len(compact), len(full))
+	}
+	// Only the accessors that make up the API of each struct are generated per struct,
+	// so the output is well under two thirds of the size
+	if len(compact) > len(full)*6/10 {
+		t.Errorf("Expected compact output to be at most 60%% of the size, got %d bytes, compared to %d", len(compact), len(full))
+	}
+	for _, expected := range []string{
+		"return runtime.Field[Identifier](&a.Node, fieldID_Alias)",
+		"return runtime.Children[dictionarySplat_expression_keywordArgument_listSplat_parenthesizedExpression](&a.Node, cursor)",
+		"return runtime.As[AliasedImport](node, kinds_AliasedImport)",
+		`runtime.NewKindSet("aliased_import")`,
+		"func ToJSONNode(node TypedNode, source []byte) *runtime.JSONNode",
+		"func LeadingExtras(node TypedNode) []*comment_lineContinuation",
+		"func Equal(node, other TypedNode,",
+		"func PathOf(node TypedNode) (runtime.Path, error)",
+		"func AllChildren(node TypedNode, cursor *tree_sitter.TreeCursor) []AnyNode",
+	} {
+		if !strings.Contains(compact, expected) {
+			t.Errorf("Expected compact output to contain %q", expected)
+		}
+	}
+	// Kind sets are precomputed rather than declared in every call, and methods that
+	// don't depend on the struct, such as JSON conversion and extras, aren't repeated
+	// for every struct
+	for _, unexpected := range []string{
+		"tsKinds := []string{",
+		") Equal(",
+		") Path()",
+		") AllChildren(",
+		") ToJSONNode(",
+		") MarshalJSON(",
+		") LeadingExtras(",
+		") TrailingExtras(",
+	} {
+		if strings.Contains(compact, unexpected) {
+			t.Errorf("Expected compact output not to contain %q", unexpected)
+		}
+	}
+
+	buildGeneratedCode(t, compact)
+}
+
+func TestRuntimeToJSONNode(t *testing.T) {
+	module, _ := parseTestPythonProgram(t)
+
+	// Finding fields and children from the grammar gives the same output as the
+	// typed accessors
+	expected, err := json.Marshal(module.ToJSONNode(testPythonProgram))
+	if err != nil {
+		t.Fatalf("Failed to marshal node: %v", err)
+	}
+	actual, err := json.Marshal(runtime.ToJSONNode(python.Grammar, module.AsNode(), testPythonProgram))
+	if err != nil {
+		t.Fatalf("Failed to marshal node: %v", err)
+	}
+	if string(actual) != string(expected) {
+		t.Errorf("Expected %s, got %s", expected, actual)
+	}
+}
+
+// buildGeneratedCode checks that generated code compiles. It's written to a package in
+// testdata, so that it's built against this module's dependencies.
+func buildGeneratedCode(t *testing.T, code string) {
+	t.Helper()
+	dir, err := os.MkdirTemp("testdata", "build-")
+	if err != nil {
+		t.Fatalf("Failed to create package directory: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	if err := os.WriteFile(filepath.Join(dir, "nodes.go"), []byte(code), 0o644); err != nil {
+		t.Fatalf("Failed to write generated code: %v", err)
+	}
+	output, err := exec.Command("go", "build", "./"+filepath.ToSlash(dir)).CombinedOutput()
+	if err != nil {
+		t.Fatalf("Generated code doesn't build: %v\n%s", err, output)
+	}
+}
+
+// clashingNodeTypes returns node types for a grammar whose kinds are named after the
+// package-level declarations gent adds. The root has a field with each of the given
+// names, holding any of the kinds.
+func clashingNodeTypes(t *testing.T, fieldNames []string, kinds ...string) []byte {
+	t.Helper()
+	children := []map[string]any{}
+	nodeTypes := []map[string]any{}
+	for _, kind := range kinds {
+		children = append(children, map[string]any{"type": kind, "named": true})
+		nodeTypes = append(nodeTypes, map[string]any{"type": kind, "named": true, "fields": map[string]any{}})
+	}
+	fields := map[string]any{}
+	for _, name := range fieldNames {
+		fields[name] = map[string]any{"multiple": false, "required": false, "types": children}
+	}
+	nodeTypes = append(nodeTypes, map[string]any{
+		"type":     "module",
+		"named":    true,
+		"root":     true,
+		"fields":   fields,
+		"children": map[string]any{"multiple": true, "required": false, "types": children},
+	})
+	data, err := json.Marshal(nodeTypes)
+	if err != nil {
+		t.Fatalf("Failed to marshal node types: %v", err)
+	}
+	return data
+}
+
+func TestGenerator_GenerateClashingKinds(t *testing.T) {
+	nodeTypes := clashingNodeTypes(
+		t,
+		// Accessors of fields named after package-level functions keep their names. This
+		// is why nodes have no `Equal`, `Hash` or `Path` methods, and `PathOf` is used to
+		// get a node's path instead.
+		[]string{"equal", "hash", "path"},
+		"any_node",
+		"typed_node",
+		"cast",
+		"must_cast",
+		"syntax_kind",
+		"extra_syntax_kinds",
+		"is_extra_syntax_kind",
+		"root_syntax_kind",
+		"language",
+		"parse",
+		"parse_file",
+		"scope_roles",
+		"build_scopes",
+		"wrap",
+		"grammar",
+		"check_tree",
+		"walk",
+		"walk_coverage",
+		"new_coverage",
+		"visitor_kinds",
+		"diff",
+		"equal",
+		"hash",
+		"resolve",
+		"path_of",
+		"select",
+		"compile_selector",
+		"must_compile_selector",
+		"dump",
+		"new_unparser",
+	)
+	code, err := gent.NewGenerator(gent.GeneratorOptions{
+		PackageName: "clashing",
+		Language:    "github.com/tree-sitter/tree-sitter-python/bindings/go",
+		Config:      gent.Config{Roles: gent.Roles{Scopes: []string{"module"}}},
+	}).Generate(nodeTypes)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// Kinds keep their names, and the helpers are renamed around them
+	for _, expected := range []string{
+		"type AnyNode struct",
+		"type AnyNode_ struct",
+		"func Cast_[T TypedNode_]",
+		"func Equal_(node, other TypedNode_,",
+		"func (m *Module) Equal() (",
+		"func (m *Module) Hash() (",
+		"func (m *Module) Path() (",
+		"func Equal__(matcher runtime.Matcher) runtime.Matcher",
+		"func PathOf_(node TypedNode_)",
+		"func Resolve_(root *tree_sitter.Node",
+		"func Select_(root *tree_sitter.Node",
+		"func MustCompileSelector_(selector string)",
+		"func Dump_(w io.Writer",
+		"func NewUnparser_(source []byte",
+	} {
+		if !strings.Contains(code, expected) {
+			t.Errorf("Expected generated code to contain %q", expected)
+		}
+	}
+	buildGeneratedCode(t, code)
+}
+
+func TestGenerator_GenerateClashingStructs(t *testing.T) {
+	// `new_expression`'s struct is the constructor of `expression`, and neither can be
+	// renamed
+	_, err := gent.NewGenerator(gent.GeneratorOptions{}).Generate(clashingNodeTypes(t, nil, "expression", "new_expression"))
+	if err == nil || !strings.Contains(err.Error(), "NewExpression") {
+		t.Errorf("Expected an error about NewExpression, got %v", err)
+	}
+}
+
+func TestGenerator_GenerateReservedFieldNames(t *testing.T) {
+	fieldTypes := []map[string]any{{"type": "expression", "named": true}}
+	field := func(multiple bool) map[string]any {
+		return map[string]any{"multiple": multiple, "required": false, "types": fieldTypes}
+	}
+	nodeTypes, err := json.Marshal([]map[string]any{
+		{"type": "expression", "named": true, "fields": map[string]any{}},
+		{"type": "comment", "named": true, "extra": true, "fields": map[string]any{}},
+		{
+			"type":  "module",
+			"named": true,
+			"root":  true,
+			// Fields named after the methods generated on structs, and one that the
+			// `Append` method of `body` would clash with
+			"fields": map[string]any{
+				"all_children":    field(true),
+				"typed_children":  field(true),
+				"typed_child":     field(false),
+				"leading_extras":  field(false),
+				"trailing_extras": field(false),
+				"body":            field(true),
+				"append_body":     field(true),
+			},
+			"children": map[string]any{"multiple": true, "required": false, "types": fieldTypes},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to marshal node types: %v", err)
+	}
+
+	code, err := gent.NewGenerator(gent.GeneratorOptions{
+		PackageName: "reserved",
+		Language:    "github.com/tree-sitter/tree-sitter-python/bindings/go",
+		Performance: true,
+	}).Generate(nodeTypes)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, expected := range []string{
+		"func (m *Module) GetAllChildren(cursor *tree_sitter.TreeCursor) []*Expression",
+		"func (m *Module) GetTypedChildren(cursor *tree_sitter.TreeCursor) []*Expression",
+		"func (m *Module) GetTypedChild() (*Expression, error)",
+		"func (m *Module) GetLeadingExtras() (*Expression, error)",
+		"func (m *Module) GetTrailingExtras() (*Expression, error)",
+		"func (m *Module) AppendBody(buf []Expression) []Expression",
+		"func (m *Module) GetAppendBody(cursor *tree_sitter.TreeCursor) []*Expression",
+		"func (m *Module) AppendGetAppendBody(buf []Expression) []Expression",
+	} {
+		if !strings.Contains(code, expected) {
+			t.Errorf("Expected generated code to contain %q", expected)
+		}
+	}
+	buildGeneratedCode(t, code)
+}
+
+// testPythonName is a hand-written typed node, as could be used for nodes the
+// generator doesn't know about.
+type testPythonName struct {
+	tree_sitter.Node
+}
+
+func (n *testPythonName) AsNode() *tree_sitter.Node {
+	return &n.Node
+}
+
+func newTestPythonName(node *tree_sitter.Node) (*testPythonName, error) {
+	if node.Kind() != "identifier" {
+		return nil, runtime.NewKindMismatchError([]string{"identifier"}, node)
+	}
+	return &testPythonName{Node: *node}, nil
+}
+
+func TestRuntimeGenericHelpers(t *testing.T) {
+	module, cursor := parseTestPythonProgram(t)
+	functionDefinition := &module.TypedChildren(cursor)[1].Node
+
+	nameField := runtime.NewFieldID("name")
+	name, err := runtime.Field[python.Identifier](functionDefinition, nameField)
+	if err != nil {
+		t.Fatalf("Failed to get name field: %v", err)
+	}
+	if text := getPythonNodeText(&name.Node); text != "main" {
+		t.Fatalf("Expected function name to be main, got %s", text)
+	}
+
+	// The registered constructor checks the kind
+	if _, err := runtime.Field[python.Block](functionDefinition, nameField); err == nil {
+		t.Fatalf("Expected an error getting an identifier as a block")
+	}
+	if _, err := runtime.Field[python.Identifier](functionDefinition, runtime.NewFieldID("return_type")); err == nil {
+		t.Fatalf("Expected an error for a missing field")
+	}
+
+	if children := runtime.Children[python.AnyNode](&module.Node, cursor); len(children) != 3 {
+		t.Fatalf("Expected 3 children, got %v", len(children))
+	}
+	// Function definitions and if statements aren't simple statements
+	if children := runtime.Children[python.SimpleStatement](&module.Node, cursor); len(children) != 1 {
+		t.Fatalf("Expected 1 simple statement, got %v", len(children))
+	}
+	if _, err := runtime.Child[python.AnyNode](&module.Node, cursor); err != nil {
+		t.Fatalf("Failed to get the first child: %v", err)
+	}
+	if _, err := runtime.Child[python.CompoundStatement](&module.Node, cursor); err == nil {
+		t.Fatalf("Expected an error, as the first child is an import statement")
+	}
+
+	// Hand-written types can be used once they're registered
+	runtime.Register(newTestPythonName)
+	customName, err := runtime.Field[testPythonName](functionDefinition, nameField)
+	if err != nil {
+		t.Fatalf("Failed to get name field as a custom type: %v", err)
+	}
+	if customName.Id() != name.Id() {
+		t.Fatalf("Expected custom name to wrap the same node")
+	}
+}
+
+func TestPythonMatch(t *testing.T) {
+	module, _ := parseTestPythonProgram(t)
+
+	// sys.exit(...), capturing the argument list
+	sysExit := python.MatchCall(
+		python.CallWithFunction(python.MatchAttribute(
+			python.AttributeWithObject(python.MatchIdentifier(runtime.Text("sys"))),
+			python.AttributeWithAttribute(python.MatchIdentifier(runtime.Text("exit"))),
+		)),
+		python.CallWithArguments(runtime.Capture("arguments", runtime.Any())),
+	)
+	matches := sysExit.FindAll(&module.Node, testPythonProgram)
+	if len(matches) != 1 {
+		t.Fatalf("Expected 1 match for sys.exit, got %d", len(matches))
+	}
+	if text := getPythonNodeText(&matches[0].Node); text != "sys.exit(main())" {
+		t.Fatalf("Expected to match sys.exit(main()), got %s", text)
+	}
+	sysExitNode := matches[0].Node
+	arguments, err := runtime.Captured[python.ArgumentList](matches[0], "arguments")
+	if err != nil {
+		t.Fatalf("Failed to get captured arguments: %v", err)
+	}
+	if text := getPythonNodeText(&arguments.Node); text != "(main())" {
+		t.Fatalf("Expected arguments to be (main()), got %s", text)
+	}
+	if _, err := runtime.Captured[python.Identifier](matches[0], "arguments"); err == nil {
+		t.Fatalf("Expected an error getting an argument list as an identifier")
+	}
+
+	// Calls to anything other than sys.exit, capturing the function
+	otherCalls := python.MatchCall(
+		runtime.Not(sysExit),
+		python.CallWithFunction(runtime.Or(
+			runtime.Capture("function", python.MatchIdentifier()),
+			python.MatchAttribute(),
+		)),
+	)
+	names := []string{}
+	for _, match := range otherCalls.FindAll(&module.Node, testPythonProgram) {
+		function, err := runtime.Captured[python.Identifier](match, "function")
+		if err != nil {
+			t.Fatalf("Failed to get captured function: %v", err)
+		}
+		names = append(names, getPythonNodeText(&function.Node))
+	}
+	if !slices.Equal(names, []string{"print", "main"}) {
+		t.Fatalf("Expected calls to print and main, got %v", names)
+	}
+
+	// Supertype matchers match any of their kinds
+	if _, ok := python.MatchExpression().Match(&arguments.Node, testPythonProgram); ok {
+		t.Fatalf("Expected an argument list not to match an expression")
+	}
+
+	// Field matchers only match their own kind, even if others have the same field
+	if matches := python.FunctionDefinitionWithName(runtime.Any()).FindAll(&module.Node, testPythonProgram); len(matches) != 1 {
+		t.Fatalf("Expected 1 function definition with a name, got %d", len(matches))
+	}
+	if matches := python.ClassDefinitionWithName(runtime.Any()).FindAll(&module.Node, testPythonProgram); len(matches) != 0 {
+		t.Fatalf("Expected no class definitions, got %d", len(matches))
+	}
+
+	// The short field matchers match any kind with the field. `Attribute_` is renamed
+	// around the `Attribute` struct.
+	shortSysExit := python.MatchCall(python.Function(python.MatchAttribute(
+		python.Object(python.MatchIdentifier(runtime.Text("sys"))),
+		python.Attribute_(python.MatchIdentifier(runtime.Text("exit"))),
+	)))
+	if matches := shortSysExit.FindAll(&module.Node, testPythonProgram); len(matches) != 1 || matches[0].Node.Id() != sysExitNode.Id() {
+		t.Fatalf("Expected short field matchers to match sys.exit, got %d matches", len(matches))
+	}
+	if matches := python.Name(runtime.Any()).FindAll(&module.Node, testPythonProgram); len(matches) < 2 {
+		t.Fatalf("Expected nodes of several kinds with a name, got %d", len(matches))
+	}
+}
+
+func TestPythonDiff(t *testing.T) {
+	oldTree, oldModule, err := python.Parse(testPythonProgram)
+	if err != nil {
+		t.Fatalf("Failed to parse old program: %v", err)
+	}
+	defer oldTree.Close()
+
+	newProgram := bytes.Replace(testPythonProgram, []byte("def main():"), []byte("def run():"), 1)
+	newProgram = bytes.Replace(newProgram, []byte("import sys\n"), []byte("import sys\nimport os\n"), 1)
+	newTree, newModule, err := python.Parse(newProgram)
+	if err != nil {
+		t.Fatalf("Failed to parse new program: %v", err)
+	}
+	defer newTree.Close()
+
+	diff := python.Diff(&oldModule.Node, &newModule.Node, testPythonProgram, newProgram)
+	if len(diff.Edits) != 2 {
+		t.Fatalf("Expected 2 edits, got:\n%s", diff)
+	}
+
+	update := diff.Edits[0]
+	if update.Kind != runtime.EditUpdate || update.NewPath.String() != "module[2]/function_definition.name/identifier" {
+		t.Fatalf("Expected the function name to be updated, got %v at %s", update.Kind, update.NewPath)
+	}
+	if name, ok := update.New.(*python.Identifier); !ok || name.Utf8Text(newProgram) != "run" {
+		t.Fatalf("Expected the new name to be an identifier, got %T", update.New)
+	}
+
+	insert := diff.Edits[1]
+	if _, ok := insert.New.(*python.ImportStatement); insert.Kind != runtime.EditInsert || !ok || insert.Old != nil {
+		t.Fatalf("Expected an import statement to be inserted, got %v of %T", insert.Kind, insert.New)
+	}
+	if !strings.Contains(diff.String(), `+ ImportStatement module[1]/import_statement: "import os"`) {
+		t.Fatalf("Expected the diff to show the inserted import, got:\n%s", diff)
+	}
+
+	// Reordered statements are moves, not deletions and insertions
+	oldSource := []byte("a = 1\nb = 2\n")
+	newSource := []byte("b = 2\na = 1\n")
+	oldTree, oldModule, _ = python.Parse(oldSource)
+	defer oldTree.Close()
+	newTree, newModule, _ = python.Parse(newSource)
+	defer newTree.Close()
+	diff = python.Diff(&oldModule.Node, &newModule.Node, oldSource, newSource)
+	if len(diff.Edits) != 1 || diff.Edits[0].Kind != runtime.EditMove {
+		t.Fatalf("Expected a single move, got:\n%s", diff)
+	}
+
+	// Whitespace between tokens isn't a change, but a changed token is
+	for newSource, edits := range map[string]int{"f(a, b)\n": 0, "f(a, b,)\n": 1} {
+		oldSource := []byte("f(a,b)\n")
+		oldTree, oldModule, _ := python.Parse(oldSource)
+		defer oldTree.Close()
+		newTree, newModule, _ := python.Parse([]byte(newSource))
+		defer newTree.Close()
+		diff := python.Diff(&oldModule.Node, &newModule.Node, oldSource, []byte(newSource))
+		if len(diff.Edits) != edits {
+			t.Fatalf("Expected %d edits from %q to %q, got:\n%s", edits, oldSource, newSource, diff)
+		}
+	}
+
+	// A node with both changed tokens and a changed child has an edit for each
+	oldSource = []byte("f(a, b)\n")
+	newSource = []byte("f(a, c,)\n")
+	oldTree, oldModule, _ = python.Parse(oldSource)
+	defer oldTree.Close()
+	newTree, newModule, _ = python.Parse(newSource)
+	defer newTree.Close()
+	diff = python.Diff(&oldModule.Node, &newModule.Node, oldSource, newSource)
+	if len(diff.Edits) != 2 {
+		t.Fatalf("Expected 2 edits, got:\n%s", diff)
+	}
+	if _, ok := diff.Edits[0].New.(*python.ArgumentList); diff.Edits[0].Kind != runtime.EditUpdate || !ok {
+		t.Fatalf("Expected the argument list's tokens to be updated first, got:\n%s", diff)
+	}
+	if identifier, ok := diff.Edits[1].New.(*python.Identifier); diff.Edits[1].Kind != runtime.EditUpdate || !ok || identifier.Utf8Text(newSource) != "c" {
+		t.Fatalf("Expected the second argument to be updated, got:\n%s", diff)
+	}
+}
+
+func TestPythonEqual(t *testing.T) {
+	source := []byte(`def f(a):
+    # Add one
+    return a + 1
+
+def f(a):
+    return a + 1
+
+def g(b):
+    return b + 1
+
+def h(b):
+    return a + 1
+`)
+	tree, module, err := python.Parse(source)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	defer tree.Close()
+	cursor := tree.Walk()
+	defer cursor.Close()
+
+	definitions := []*python.FunctionDefinition{}
+	for _, child := range module.TypedChildren(cursor) {
+		definition, err := python.Cast[*python.FunctionDefinition](&child.Node)
+		if err != nil {
+			t.Fatalf("Failed to cast child: %v", err)
+		}
+		definitions = append(definitions, definition)
+	}
+
+	tests := []struct {
+		name    string
+		a, b    int
+		options runtime.EqualOptions
+		equal   bool
+	}{
+		{"comments differ", 0, 1, runtime.EqualOptions{}, false},
+		{"comments ignored", 0, 1, runtime.EqualOptions{IgnoreExtras: true}, true},
+		{"names differ", 1, 2, runtime.EqualOptions{}, false},
+		{"names ignored", 1, 2, runtime.EqualOptions{IgnoreIdentifiers: true}, true},
+		{"names renamed inconsistently", 2, 3, runtime.EqualOptions{IgnoreIdentifiers: true}, false},
+		{"identifier kinds overridden", 1, 2, runtime.EqualOptions{IgnoreIdentifiers: true, IdentifierKinds: []runtime.SyntaxKind{"integer"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := definitions[tt.a], definitions[tt.b]
+			if equal := python.Equal(a, b, source, source, tt.options); equal != tt.equal {
+				t.Fatalf("Expected Equal to be %v", tt.equal)
+			}
+			if tt.equal && python.Hash(a, source, tt.options) != python.Hash(b, source, tt.options) {
+				t.Fatalf("Expected equal nodes to have the same hash")
+			}
+			if !tt.equal && python.Hash(a, source, tt.options) == python.Hash(b, source, tt.options) {
+				t.Fatalf("Expected different nodes to have different hashes")
+			}
+		})
+	}
+
+	// Whitespace between tokens is never compared
+	other := []byte("def f(a):\n    return a+1\n")
+	otherTree, otherModule, _ := python.Parse(other)
+	defer otherTree.Close()
+	if !python.Equal(definitions[1], &otherModule.TypedChildren(cursor)[0], source, other, runtime.EqualOptions{}) {
+		t.Fatalf("Expected definitions differing only in whitespace to be equal")
+	}
+}
+
+func TestPythonPath(t *testing.T) {
+	module, cursor := parseTestPythonProgram(t)
+	functionDefinition, err := python.Cast[*python.FunctionDefinition](&module.TypedChildren(cursor)[1].Node)
+	if err != nil {
+		t.Fatalf("Failed to cast function definition: %v", err)
+	}
+	name, err := functionDefinition.Name()
+	if err != nil {
+		t.Fatalf("Failed to get function name: %v", err)
+	}
+
+	path, err := python.PathOf(name)
+	if err != nil {
+		t.Fatalf("Failed to get path: %v", err)
+	}
+	if path.String() != "module[1]/function_definition.name/identifier" {
+		t.Fatalf("Unexpected path %s", path)
+	}
+
+	// Paths round-trip through text and JSON
+	parsed, err := runtime.ParsePath(path.String())
+	if err != nil || !reflect.DeepEqual(parsed, path) {
+		t.Fatalf("Expected parsed path to equal %s, got %s: %v", path, parsed, err)
+	}
+	encoded, err := json.Marshal(path)
+	if err != nil || string(encoded) != `"module[1]/function_definition.name/identifier"` {
+		t.Fatalf("Unexpected JSON %s: %v", encoded, err)
+	}
+	var decoded runtime.Path
+	if err := json.Unmarshal(encoded, &decoded); err != nil || !reflect.DeepEqual(decoded, path) {
+		t.Fatalf("Expected decoded path to equal %s, got %s: %v", path, decoded, err)
+	}
+
+	// Paths survive reformatting, and adding and removing comments, as extras are
+	// counted separately
+	reformatted := []byte("import sys\n# Set up\n\n# Run\ndef main():\n  print('Hello, world!'); return 0\nif __name__ == '__main__': sys.exit(main())\n")
+	tree, _, err := python.Parse(reformatted)
+	if err != nil {
+		t.Fatalf("Failed to parse reformatted program: %v", err)
+	}
+	defer tree.Close()
+	resolved, err := python.Resolve(tree.RootNode(), path)
+	if err != nil {
+		t.Fatalf("Failed to resolve path: %v", err)
+	}
+	if identifier, ok := resolved.(*python.Identifier); !ok || identifier.Utf8Text(reformatted) != "main" {
+		t.Fatalf("Expected to resolve the function name, got %T", resolved)
+	}
+
+	// Extras have paths of their own
+	comments := functionDefinition.LeadingExtras()
+	if len(comments) != 1 {
+		t.Fatalf("Expected the function to have a leading comment, got %d", len(comments))
+	}
+	commentPath, err := python.PathOf(comments[0])
+	if err != nil || commentPath.String() != "module{0}/comment" {
+		t.Fatalf("Unexpected comment path %s: %v", commentPath, err)
+	}
+	parsed, err = runtime.ParsePath(commentPath.String())
+	if err != nil || !reflect.DeepEqual(parsed, commentPath) {
+		t.Fatalf("Expected parsed path to equal %s, got %s: %v", commentPath, parsed, err)
+	}
+	resolved, err = python.Resolve(tree.RootNode(), runtime.Path{Root: "module", Steps: []runtime.PathStep{{Index: 1, Extra: true}}})
+	if err != nil || resolved.AsNode().Utf8Text(reformatted) != "# Run" {
+		t.Fatalf("Expected to resolve the second comment, got %v", err)
+	}
+
+	// Kinds are optional, and unquoted kinds are checked
+	integerPath, err := runtime.ParsePath("module[1].body[1][0]")
+	if err != nil {
+		t.Fatalf("Failed to parse path: %v", err)
+	}
+	if _, err := python.Resolve(tree.RootNode(), integerPath); err == nil {
+		t.Fatalf("Expected an error, as the body holds a single block")
+	}
+	integerPath, _ = runtime.ParsePath("module[1].body[1]/return_statement[0]")
+	if _, err := python.Resolve(tree.RootNode(), integerPath); err == nil {
+		t.Fatalf("Expected an error resolving a block as a return statement")
+	}
+	integerPath, _ = runtime.ParsePath("module[1].body/block[1]/return_statement[0]/integer")
+	integer, err := python.Resolve(tree.RootNode(), integerPath)
+	if err != nil {
+		t.Fatalf("Failed to resolve path: %v", err)
+	}
+	if _, ok := integer.(*python.Integer); !ok {
+		t.Fatalf("Expected an integer, got %T", integer)
+	}
+
+	for _, invalid := range []string{"", "module.", "module[x]", "module/block", `module.operator/"+`, "module.body{0}", "module{0"} {
+		if _, err := runtime.ParsePath(invalid); err == nil {
+			t.Fatalf("Expected an error parsing %q", invalid)
+		}
+	}
+}
+
+func TestPythonSelect(t *testing.T) {
+	source := []byte(`class Greeter:
+    def __init__(self, name):
+        def helper():
+            pass
+        self.name = name
+
+    def greet(self):
+        print("Hello", self.name)
+
+def __init__():
+    pass
+`)
+	tree, module, err := python.Parse(source)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	defer tree.Close()
+
+	tests := []struct {
+		selector string
+		expected []string
+	}{
+		{`class_definition > block function_definition[name="__init__"]`, []string{"__init__"}},
+		{`class_definition function_definition`, []string{"__init__", "helper", "greet"}},
+		{`class_definition > block > function_definition`, []string{"__init__", "greet"}},
+		{`function_definition[name!="__init__"], module > function_definition`, []string{"helper", "greet", "__init__"}},
+		{`block > *[name="helper"]`, []string{"helper"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			nodes, err := python.Select(&module.Node, source, python.MustCompileSelector(tt.selector))
+			if err != nil {
+				t.Fatalf("Failed to select nodes: %v", err)
+			}
+			names := []string{}
+			for _, node := range nodes {
+				name, err := node.(*python.FunctionDefinition).Name()
+				if err != nil {
+					t.Fatalf("Failed to get function name: %v", err)
+				}
+				names = append(names, name.Utf8Text(source))
+			}
+			if !slices.Equal(names, tt.expected) {
+				t.Fatalf("Expected %v, got %v", tt.expected, names)
+			}
+		})
+	}
+
+	// Supertypes match any of their kinds
+	nodes, err := python.Select(&module.Node, source, python.MustCompileSelector("function_definition > block > _simple_statement"))
+	if err != nil || len(nodes) != 4 {
+		t.Fatalf("Expected 4 simple statements, got %d: %v", len(nodes), err)
+	}
+
+	_, err = python.CompileSelector("class_definition > functon_definition")
+	var selectorErr *runtime.SelectorError
+	if !errors.As(err, &selectorErr) {
+		t.Fatalf("Expected a selector error, got %v", err)
+	}
+	if selectorErr.Offset != 19 || !slices.Contains(selectorErr.Suggestions, "function_definition") {
+		t.Fatalf("Expected function_definition to be suggested at offset 19, got %v", err)
+	}
+	_, err = python.CompileSelector("function_definition[nme]")
+	if !errors.As(err, &selectorErr) || !slices.Equal(selectorErr.Suggestions, []string{"name"}) {
+		t.Fatalf("Expected name to be suggested, got %v", err)
+	}
+	for _, invalid := range []string{"", "call >", "call[function", `call[function=x]`, "call,", "call[superclasses]"} {
+		if _, err := python.CompileSelector(invalid); err == nil {
+			t.Fatalf("Expected an error compiling %q", invalid)
+		}
+	}
+}
+
+func TestPythonSelectWithErrors(t *testing.T) {
+	source := []byte("def f(:\n    pass\nx = )\n")
+	tree, module, err := python.Parse(source)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	defer tree.Close()
+	if !tree.RootNode().HasError() {
+		t.Fatalf("Expected the program to have errors")
+	}
+
+	// ERROR and MISSING nodes can't be wrapped, so `*` doesn't match them
+	for _, selector := range []string{"*", "module > *"} {
+		nodes, err := python.Select(&module.Node, source, python.MustCompileSelector(selector))
+		if err != nil {
+			t.Fatalf("Failed to select %q: %v", selector, err)
+		}
+		if len(nodes) == 0 {
+			t.Fatalf("Expected %q to match nodes", selector)
+		}
+		for _, node := range nodes {
+			if node.AsNode().IsError() || node.AsNode().IsMissing() {
+				t.Fatalf("Expected %q not to match %s", selector, node.AsNode().Kind())
+			}
+		}
+	}
+}
+
+func TestPythonDump(t *testing.T) {
+	module, cursor := parseTestPythonProgram(t)
+	functionDefinition := module.TypedChildren(cursor)[1]
+
+	output := strings.Builder{}
+	err := python.Dump(&output, &functionDefinition, testPythonProgram, runtime.DumpOptions{HideUnnamed: true, MaxDepth: 2})
+	if err != nil {
+		t.Fatalf("Failed to dump tree: %v", err)
+	}
+	expected := `FunctionDefinition 4:1-6:13
+  name: Identifier 4:5-4:9 "main"
+  parameters: Parameters 4:9-4:11
+  body: Block 5:5-6:13
+    ExpressionStatement 5:5-5:27 (1 child hidden)
+    ReturnStatement 6:5-6:13 (1 child hidden)
+`
+	if output.String() != expected {
+		t.Fatalf("Expected dump:\n%s\ngot:\n%s", expected, output.String())
+	}
+
+	output.Reset()
+	err = python.Dump(&output, &functionDefinition, testPythonProgram, runtime.DumpOptions{Indent: "\t", Color: true})
+	if err != nil {
+		t.Fatalf("Failed to dump tree: %v", err)
+	}
+	if !strings.Contains(output.String(), "\t\x1b[36mparameters:\x1b[0m \x1b[1mParameters\x1b[0m") {
+		t.Fatalf("Expected coloured field and struct names, got:\n%s", output.String())
+	}
+	if !strings.Contains(output.String(), "\n\tUnnamed_Def ") {
+		t.Fatalf("Expected unnamed tokens to be shown, got:\n%s", output.String())
+	}
+}
+
+type testPythonFormatter struct {
+	runtime.Formatter
+}
+
+func (f *testPythonFormatter) VisitComment(node *python.Comment) bool {
+	f.Write(strings.ToUpper(f.Text(&node.Node)))
+	return false
+}
+
+func TestPythonUnparse(t *testing.T) {
+	module, cursor := parseTestPythonProgram(t)
+
+	// Without changes, the source is unchanged
+	if output := python.NewUnparser(testPythonProgram, nil).Bytes(&module.Node); !bytes.Equal(output, testPythonProgram) {
+		t.Fatalf("Expected the original source, got:\n%s", output)
+	}
+
+	unparser := python.NewUnparser(testPythonProgram, &testPythonFormatter{})
+	for _, match := range python.MatchIdentifier(runtime.Text("main")).FindAll(&module.Node, testPythonProgram) {
+		unparser.ReplaceText(&match.Node, "run")
+	}
+
+	functionDefinition, err := python.Cast[*python.FunctionDefinition](&module.TypedChildren(cursor)[1].Node)
+	if err != nil {
+		t.Fatalf("Failed to cast function definition: %v", err)
+	}
+	body, err := functionDefinition.Body()
+	if err != nil {
+		t.Fatalf("Failed to get function body: %v", err)
+	}
+	statements := body.TypedChildren(cursor)
+	unparser.Delete(&statements[0].Node)
+	unparser.InsertBefore(&statements[1].Node, func(p *runtime.Printer) {
+		p.Write(`log("done")` + "\n" + p.LineIndent(&statements[1].Node))
+	})
+
+	sysExit := python.MatchCall(python.CallWithArguments(runtime.Capture("arguments", runtime.Any())))
+	for _, match := range sysExit.FindAll(&module.Node, testPythonProgram) {
+		if !strings.HasPrefix(getPythonNodeText(&match.Node), "sys.exit") {
+			continue
+		}
+		arguments := match.Captures["arguments"]
+		unparser.Replace(&match.Node, func(p *runtime.Printer) {
+			p.Write("raise SystemExit")
+			p.Print(&arguments)
+		})
+	}
+
+	expected := `import sys
+
+# ENTRY POINT
+def run():
+    log("done")
+    return 0
+
+if __name__ == "__main__":
+    raise SystemExit(run())
+`
+	output := strings.Builder{}
+	if err := unparser.Unparse(&output, &module.Node); err != nil {
+		t.Fatalf("Failed to unparse: %v", err)
+	}
+	if output.String() != expected {
+		t.Fatalf("Expected:\n%s\ngot:\n%s", expected, output.String())
+	}
+}
+
+func TestPythonBuildScopes(t *testing.T) {
+	source := []byte(`import os.path as p
+import sys
+
+x = 1
+
+def f(a, b=x):
+    y = a + b
+    def g():
+        return y + x + z
+    return g
+
+class C:
+    def m(self, n):
+        return self.value + n + len(sys.argv)
+
+for i, j in []:
+    print(f(i, b=j))
+`)
+	tree, module, err := python.Parse(source)
+	if err != nil {
+		t.Fatalf("Failed to parse program: %v", err)
+	}
+	defer tree.Close()
+
+	scopes := python.BuildScopes(&module.Node, source)
+	names := func(declarations []*runtime.Declaration) []string {
+		result := []string{}
+		for _, declaration := range declarations {
+			result = append(result, declaration.Name)
+		}
+		return result
+	}
+
+	root := scopes.Root
+	if _, ok := root.Typed.(*python.Module); !ok {
+		t.Fatalf("Expected the root scope to be a module, got %T", root.Typed)
+	}
+	if declared := names(root.Declarations); !slices.Equal(declared, []string{"p", "sys", "x", "f", "C", "i", "j"}) {
+		t.Fatalf("Unexpected module declarations %v", declared)
+	}
+	if len(root.Children) != 2 || len(root.Children[0].Children) != 1 || len(root.Children[1].Children) != 1 {
+		t.Fatalf("Expected module > (f > g, C > m) scopes")
+	}
+	f, g := root.Children[0], root.Children[0].Children[0]
+	if _, ok := f.Typed.(*python.FunctionDefinition); !ok {
+		t.Fatalf("Expected f's scope to be a function definition, got %T", f.Typed)
+	}
+	if declared := names(f.Declarations); !slices.Equal(declared, []string{"a", "b", "y", "g"}) {
+		t.Fatalf("Unexpected declarations in f %v", declared)
+	}
+	if _, ok := f.Declarations[0].Typed.(*python.Parameters); !ok {
+		t.Fatalf("Expected a to be declared by parameters, got %T", f.Declarations[0].Typed)
+	}
+
+	resolved := map[string]string{}
+	unresolved := []string{}
+	for _, reference := range scopes.References {
+		if _, ok := reference.Typed.(*python.Identifier); !ok {
+			t.Fatalf("Expected references to be identifiers, got %T", reference.Typed)
+		}
+		if reference.Declaration == nil {
+			unresolved = append(unresolved, reference.Name)
+			continue
+		}
+		resolved[reference.Name+" in "+reference.Scope.Node.Kind()] = reference.Declaration.Scope.Node.Kind()
+	}
+	if !slices.Equal(unresolved, []string{"z", "len", "print"}) {
+		t.Fatalf("Unexpected unresolved references %v", unresolved)
+	}
+	if resolved["y in function_definition"] != "function_definition" || resolved["sys in function_definition"] != "module" {
+		t.Fatalf("Unexpected resolved references %v", resolved)
+	}
+	for _, reference := range scopes.References {
+		if reference.Name == "value" || reference.Name == "argv" {
+			t.Fatalf("Expected attribute names to be ignored")
+		}
+	}
+
+	x := root.Lookup("x")
+	if x == nil || len(x.References) != 2 {
+		t.Fatalf("Expected x to be referenced twice, got %v", x)
+	}
+	if scope := scopes.ScopeOf(&x.References[1].Node); scope != g {
+		t.Fatalf("Expected the second reference to x to be in g")
+	}
+
+	// Roles are checked against the node types
+	generator := gent.NewGenerator(gent.GeneratorOptions{
+		PackageName: "python",
+		Config: gent.Config{Roles: gent.Roles{
+			Scopes:      []string{"module"},
+			Definitions: map[string]string{"function_definition": "nme"},
+		}},
+	})
+	if _, err := generator.Generate(pythonNodeTypes); err == nil || !strings.Contains(err.Error(), "no field nme") {
+		t.Fatalf("Expected an error for an unknown field, got %v", err)
+	}
+}
@@ -0,0 +1,290 @@
+package gent
+
+import (
+	"fmt"
+	"strings"
+)
+
+// GraphOptions configures the graph formats.
+type GraphOptions struct {
+	// Only include kinds reachable from this Tree-sitter kind. If empty, every kind is
+	// included.
+	Root string
+	// The maximum number of edges followed from the root kind. If zero, there's no
+	// limit.
+	Depth int
+	// Unions with at least this many members are highlighted. Defaults to 10.
+	LargeUnionSize int
+}
+
+// graphBackend renders the supertype hierarchy and the field and children edges of the
+// node type model as a DOT or Mermaid graph.
+type graphBackend struct {
+	options GeneratorOptions
+	mermaid bool
+}
+
+type graphNodeStyle int
+
+const (
+	graphNodeStyleNode graphNodeStyle = iota
+	graphNodeStyleToken
+	graphNodeStyleSupertype
+	graphNodeStyleUnion
+	graphNodeStyleLargeUnion
+	graphNodeStyleUnknown
+)
+
+type graphNode struct {
+	// Struct name
+	id    string
+	style graphNodeStyle
+}
+
+type graphEdge struct {
+	from string
+	to   string
+	// Field name, "children", or empty for union and supertype members
+	label string
+}
+
+type graph struct {
+	// Nodes in the order they were added
+	nodes []graphNode
+	edges []graphEdge
+}
+
+func (b *graphBackend) render(nodeTypes nodeTypes, nm *nodeMap) (string, error) {
+	largeUnionSize := b.options.Graph.LargeUnionSize
+	if largeUnionSize == 0 {
+		largeUnionSize = 10
+	}
+
+	g, err := buildGraph(nodeTypes, nm, largeUnionSize)
+	if err != nil {
+		return "", err
+	}
+
+	if b.options.Graph.Root != "" {
+		root, ok := nm.getStructName(b.options.Graph.Root, true)
+		if !ok {
+			return "", fmt.Errorf("Unknown root kind %s", b.options.Graph.Root)
+		}
+		g = g.reachableFrom(root, b.options.Graph.Depth)
+	}
+
+	name := "node_types"
+	if b.options.PackageName != "" {
+		name = b.options.PackageName
+	}
+
+	if b.mermaid {
+		return g.renderMermaid(), nil
+	}
+	return g.renderDOT(name), nil
+}
+
+func buildGraph(nodeTypes nodeTypes, nm *nodeMap, largeUnionSize int) (graph, error) {
+	g := graph{}
+	seen := map[string]bool{}
+	addNode := func(id string, style graphNodeStyle) {
+		if seen[id] {
+			return
+		}
+		seen[id] = true
+		g.nodes = append(g.nodes, graphNode{id: id, style: style})
+	}
+	unionStyle := func(members []nodeChildType, style graphNodeStyle) graphNodeStyle {
+		if len(members) >= largeUnionSize {
+			return graphNodeStyleLargeUnion
+		}
+		return style
+	}
+
+	// Edges to a union go through the union's own node, so that nested unions are
+	// visible in the graph.
+	addTypesEdge := func(from string, label string, types []nodeChildType) error {
+		typeName, ok := nm.getTypeName(types)
+		if !ok {
+			return fmt.Errorf("Failed to find type name for %s.%s", from, label)
+		}
+		g.edges = append(g.edges, graphEdge{from: from, to: typeName, label: label})
+		return nil
+	}
+
+	for _, nodeType := range nodeTypes {
+		structName, ok := nm.getStructName(nodeType.Type, nodeType.Named)
+		if !ok {
+			return graph{}, fmt.Errorf("Failed to find struct name for %s", nodeType.Type)
+		}
+
+		if nodeType.Subtypes != nil {
+			addNode(structName, unionStyle(nodeType.Subtypes, graphNodeStyleSupertype))
+			continue
+		}
+
+		// Unnamed tokens are only added once something refers to them
+		if !nodeType.Named {
+			continue
+		}
+		addNode(structName, graphNodeStyleNode)
+
+		for name, field := range nodeType.Fields.FromOldest() {
+			if err := addTypesEdge(structName, name, field.Types); err != nil {
+				return graph{}, err
+			}
+		}
+		if len(nodeType.Children.Types) > 0 {
+			if err := addTypesEdge(structName, "children", nodeType.Children.Types); err != nil {
+				return graph{}, err
+			}
+		}
+	}
+
+	unions := []unionType{}
+	for _, supertype := range nm.supertypes.FromOldest() {
+		unions = append(unions, supertype)
+	}
+	for _, unionType := range nm.unionTypes.FromOldest() {
+		addNode(unionType.name, unionStyle(unionType.members, graphNodeStyleUnion))
+		unions = append(unions, unionType)
+	}
+	for _, unionType := range unions {
+		for _, member := range unionType.members {
+			memberName, ok := nm.getStructName(member.Type, member.Named)
+			if !ok {
+				return graph{}, fmt.Errorf("Failed to find struct name for %s.%s", unionType.name, member.Type)
+			}
+			g.edges = append(g.edges, graphEdge{from: unionType.name, to: memberName})
+		}
+	}
+
+	for _, structName := range nm.unknown.FromOldest() {
+		addNode(structName, graphNodeStyleUnknown)
+	}
+
+	// Anything left is an unnamed token referenced by an edge
+	for _, edge := range g.edges {
+		addNode(edge.to, graphNodeStyleToken)
+	}
+
+	return g, nil
+}
+
+// reachableFrom returns the subgraph of nodes reachable from the given root in at most
+// depth edges. A depth of zero means there's no limit.
+func (g graph) reachableFrom(root string, depth int) graph {
+	outgoing := map[string][]graphEdge{}
+	for _, edge := range g.edges {
+		outgoing[edge.from] = append(outgoing[edge.from], edge)
+	}
+
+	distances := map[string]int{root: 0}
+	queue := []string{root}
+	edges := []graphEdge{}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if depth > 0 && distances[id] >= depth {
+			continue
+		}
+		for _, edge := range outgoing[id] {
+			edges = append(edges, edge)
+			if _, ok := distances[edge.to]; !ok {
+				distances[edge.to] = distances[id] + 1
+				queue = append(queue, edge.to)
+			}
+		}
+	}
+
+	output := graph{edges: edges}
+	for _, node := range g.nodes {
+		if _, ok := distances[node.id]; ok {
+			output.nodes = append(output.nodes, node)
+		}
+	}
+	return output
+}
+
+func (g graph) renderDOT(name string) string {
+	output := &strings.Builder{}
+	fmt.Fprintf(output, "digraph %q {\n  rankdir=LR;\n  node [shape=box];\n\n", name)
+
+	for _, node := range g.nodes {
+		attributes := ""
+		switch node.style {
+		case graphNodeStyleToken:
+			attributes = ", shape=plaintext"
+		case graphNodeStyleSupertype:
+			attributes = ", shape=ellipse, style=filled, fillcolor=lightblue"
+		case graphNodeStyleUnion:
+			attributes = ", shape=ellipse"
+		case graphNodeStyleLargeUnion:
+			attributes = ", shape=ellipse, style=filled, fillcolor=orange"
+		case graphNodeStyleUnknown:
+			attributes = ", style=filled, fillcolor=red"
+		}
+		fmt.Fprintf(output, "  %q [label=%q%s];\n", node.id, node.id, attributes)
+	}
+
+	output.WriteString("\n")
+	for _, edge := range g.edges {
+		if edge.label == "" {
+			fmt.Fprintf(output, "  %q -> %q [style=dashed];\n", edge.from, edge.to)
+			continue
+		}
+		fmt.Fprintf(output, "  %q -> %q [label=%q];\n", edge.from, edge.to, edge.label)
+	}
+
+	output.WriteString("}\n")
+	return output.String()
+}
+
+func (g graph) renderMermaid() string {
+	output := &strings.Builder{}
+	output.WriteString("graph LR\n")
+
+	// Mermaid IDs are prefixed to avoid clashing with keywords like `end`
+	id := func(structName string) string {
+		return "k_" + structName
+	}
+
+	classes := map[graphNodeStyle][]string{}
+	for _, node := range g.nodes {
+		label := strings.NewReplacer(`"`, "#quot;").Replace(node.id)
+		switch node.style {
+		case graphNodeStyleSupertype, graphNodeStyleUnion, graphNodeStyleLargeUnion:
+			fmt.Fprintf(output, "  %s([\"%s\"])\n", id(node.id), label)
+		default:
+			fmt.Fprintf(output, "  %s[\"%s\"]\n", id(node.id), label)
+		}
+		classes[node.style] = append(classes[node.style], id(node.id))
+	}
+
+	for _, edge := range g.edges {
+		if edge.label == "" {
+			fmt.Fprintf(output, "  %s -.-> %s\n", id(edge.from), id(edge.to))
+			continue
+		}
+		fmt.Fprintf(output, "  %s -- %s --> %s\n", id(edge.from), edge.label, id(edge.to))
+	}
+
+	classDefs := []struct {
+		style graphNodeStyle
+		name  string
+		def   string
+	}{
+		{graphNodeStyleSupertype, "supertype", "fill:#add8e6"},
+		{graphNodeStyleLargeUnion, "largeUnion", "fill:#ffa500"},
+		{graphNodeStyleUnknown, "unknown", "fill:#ff6666"},
+	}
+	for _, classDef := range classDefs {
+		if len(classes[classDef.style]) == 0 {
+			continue
+		}
+		fmt.Fprintf(output, "  classDef %s %s\n", classDef.name, classDef.def)
+		fmt.Fprintf(output, "  class %s %s\n", strings.Join(classes[classDef.style], ","), classDef.name)
+	}
+
+	return output.String()
+}
@@ -0,0 +1,108 @@
+package gent
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/dave/jennifer/jen"
+)
+
+// Name of the package-level variable holding the bound language, used to resolve
+// field and kind IDs.
+const boundLanguageVarName = "boundLanguage"
+
+// languagePackageName guesses the name of the Go package at the given import path.
+// Tree-sitter grammars put their Go bindings in `bindings/go`, so the package is named
+// after the repository, e.g. `tree_sitter_python`.
+func languagePackageName(importPath string) string {
+	name := path.Base(importPath)
+	if name == "go" && path.Base(path.Dir(importPath)) == "bindings" {
+		name = path.Base(path.Dir(path.Dir(importPath)))
+	}
+	return strings.ReplaceAll(name, "-", "_")
+}
+
+// writeLanguageFunctions adds the `Language`, `Parse` and `ParseFile` functions, which
+// bind the generated package to the Go bindings of its grammar, and registers the
+// binding with the runtime package.
+func writeLanguageFunctions(file *jen.File, importPath string, nm *nodeMap) error {
+	if nm.root == "" {
+		return fmt.Errorf("Cannot bind to language %s, as no kind is marked as the root in node-types.json", importPath)
+	}
+	rootStructName, ok := nm.getStructName(nm.root, true)
+	if !ok {
+		return fmt.Errorf("Failed to find struct name for root kind %s", nm.root)
+	}
+
+	file.ImportAlias(importPath, languagePackageName(importPath))
+
+	tsQual := func(name string) *jen.Statement {
+		return jen.Qual("github.com/tree-sitter/go-tree-sitter", name)
+	}
+	results := []jen.Code{jen.Op("*").Add(tsQual("Tree")), jen.Op("*").Id(rootStructName), jen.Error()}
+	languageName := nm.names.get("Language")
+	parseName := nm.names.get("Parse")
+	parseFileName := nm.names.get("ParseFile")
+	boundLanguageName := nm.names.get(boundLanguageVarName)
+
+	file.Var().Id(boundLanguageName).Op("=").Id(languageName).Call()
+
+	writeDocComment(file, languageName+" returns the Tree-sitter language the types were generated from.")
+	file.Func().Id(languageName).Params().Op("*").Add(tsQual("Language")).Block(
+		jen.Return(tsQual("NewLanguage").Call(jen.Qual(importPath, "Language").Call())),
+	)
+
+	writeDocComment(
+		file,
+		fmt.Sprintf(
+			"%s parses the source code, returning the tree and its root %s. The caller must close the tree when it's no longer needed.",
+			parseName,
+			rootStructName,
+		),
+		"Syntax errors don't cause an error to be returned. Instead, the tree contains `ERROR` and `MISSING` nodes, as with any Tree-sitter parser.",
+	)
+	file.Func().Id(parseName).Params(jen.Id("source").Index().Byte()).Parens(jen.List(results...)).Block(
+		jen.Id("parser").Op(":=").Add(tsQual("NewParser")).Call(),
+		jen.Defer().Id("parser").Dot("Close").Call(),
+		jen.If(
+			jen.Err().Op(":=").Id("parser").Dot("SetLanguage").Call(jen.Id(boundLanguageName)),
+			jen.Err().Op("!=").Nil(),
+		).Block(
+			jen.Return(jen.Nil(), jen.Nil(), jen.Qual("fmt", "Errorf").Call(jen.Lit("Failed to set language: %w"), jen.Err())),
+		),
+		jen.Line(),
+		jen.Id("tree").Op(":=").Id("parser").Dot("Parse").Call(jen.Id("source"), jen.Nil()),
+		jen.If(jen.Id("tree").Op("==").Nil()).Block(
+			jen.Return(jen.Nil(), jen.Nil(), jen.Qual("errors", "New").Call(jen.Lit("Failed to parse source"))),
+		),
+		jen.List(jen.Id("root"), jen.Err()).Op(":=").Id(constructorName(rootStructName)).Call(jen.Id("tree").Dot("RootNode").Call()),
+		jen.If(jen.Err().Op("!=").Nil()).Block(
+			jen.Id("tree").Dot("Close").Call(),
+			jen.Return(jen.Nil(), jen.Nil(), jen.Err()),
+		),
+		jen.Return(jen.Id("tree"), jen.Id("root"), jen.Nil()),
+	)
+
+	writeDocComment(file, parseFileName+" reads and parses the file at the given path, as with `"+parseName+"`.")
+	file.Func().Id(parseFileName).Params(jen.Id("path").String()).Parens(jen.List(results...)).Block(
+		jen.List(jen.Id("source"), jen.Err()).Op(":=").Qual("os", "ReadFile").Call(jen.Id("path")),
+		jen.If(jen.Err().Op("!=").Nil()).Block(
+			jen.Return(jen.Nil(), jen.Nil(), jen.Qual("fmt", "Errorf").Call(jen.Lit("Failed to read from %s: %w"), jen.Id("path"), jen.Err())),
+		),
+		jen.Return(jen.Id(parseName).Call(jen.Id("source"))),
+	)
+
+	file.Comment("Register the binding, so that tools such as `gent corpus` can use the package.")
+	file.Func().Id("init").Params().Block(
+		jen.Qual(runtimePackage, "RegisterBinding").Call(jen.Op("&").Qual(runtimePackage, "Binding").Values(jen.Dict{
+			jen.Id("Language"): jen.Id(boundLanguageName),
+			jen.Id("Grammar"):  jen.Id(nm.names.get("Grammar")),
+			jen.Id("Wrap"): jen.Func().Params(jen.Id("node").Op("*").Add(tsQual("Node"))).
+				Params(jen.Qual(runtimePackage, "TypedNode"), jen.Error()).
+				Block(jen.Return(jen.Id(nm.names.get("Wrap")).Call(jen.Id("node")))),
+		})),
+	)
+
+	return nil
+}
@@ -0,0 +1,126 @@
+package gent
+
+import (
+	"fmt"
+
+	"github.com/dave/jennifer/jen"
+)
+
+// matchFuncName returns the name of the function matching nodes of the given struct.
+func matchFuncName(structName string) string {
+	return "Match" + structName
+}
+
+// withFuncName returns the name of the function matching nodes of the given struct by a
+// child in the field with the given Tree-sitter name.
+func withFuncName(structName string, tsFieldName string) string {
+	return structName + "With" + createExportedName(tsFieldName)
+}
+
+// writeMatchFunctions adds a `Match<Struct>` function for each named kind and
+// supertype, and a `<Struct>With<Field>` function for each field of each kind. They
+// build `runtime.Matcher`s which can be composed with the combinators in the runtime.
+//
+// The short `<Field>` functions, which don't check the kind, are added by
+// writeFieldMatchFunctions.
+func writeMatchFunctions(file *jen.File, nodeTypes nodeTypes, nm *nodeMap) error {
+	matcher := jen.Qual(runtimePackage, "Matcher")
+	structNames := map[string]bool{}
+	for _, nodeType := range nodeTypes {
+		structName, _ := nm.getStructName(nodeType.Type, nodeType.Named)
+		structNames[structName] = true
+	}
+	checkName := func(name string) error {
+		if structNames[name] {
+			return fmt.Errorf("Matcher %s clashes with the struct of the same name", name)
+		}
+		return nil
+	}
+
+	for _, nodeType := range nodeTypes {
+		if !nodeType.Named {
+			continue
+		}
+		structName, _ := nm.getStructName(nodeType.Type, nodeType.Named)
+		name := matchFuncName(structName)
+		if err := checkName(name); err != nil {
+			return err
+		}
+
+		kinds := []jen.Code{jen.Id(syntaxKindConstName(structName))}
+		description := fmt.Sprintf("`%s` nodes", nodeType.Type)
+		if nodeType.Subtypes != nil {
+			kinds = []jen.Code{}
+			for _, tsKind := range nm.getTSRecursiveTSKinds(nodeType.Type) {
+				kinds = append(kinds, jen.Lit(tsKind))
+			}
+			description = fmt.Sprintf("nodes of any kind in the `%s` supertype", nodeType.Type)
+		}
+
+		writeDocComment(file, fmt.Sprintf("%s matches %s that match all of the given matchers.", name, description))
+		file.Func().Id(name).Params(jen.Id("matchers").Op("...").Add(matcher)).Add(matcher).Block(
+			jen.Return(jen.Qual(runtimePackage, "Kind").Call(
+				jen.Index().Qual(runtimePackage, "SyntaxKind").Values(kinds...),
+				jen.Id("matchers").Op("..."),
+			)),
+		)
+	}
+
+	for _, nodeType := range nodeTypes {
+		if !nodeType.Named {
+			continue
+		}
+		structName, _ := nm.getStructName(nodeType.Type, nodeType.Named)
+		for fieldName := range nodeType.Fields.FromOldest() {
+			name := withFuncName(structName, fieldName)
+			if err := checkName(name); err != nil {
+				return err
+			}
+
+			writeDocComment(
+				file,
+				fmt.Sprintf("%s matches `%s` nodes with a child in the `%s` field that matches the given matcher. If the field holds multiple nodes, any of them can match.", name, nodeType.Type, fieldName),
+			)
+			file.Func().Id(name).Params(jen.Id("matcher").Add(matcher)).Add(matcher).Block(
+				jen.Return(jen.Qual(runtimePackage, "Kind").Call(
+					jen.Index().Qual(runtimePackage, "SyntaxKind").Values(jen.Id(syntaxKindConstName(structName))),
+					jen.Qual(runtimePackage, "InField").Call(jen.Lit(fieldName), jen.Id("matcher")),
+				)),
+			)
+		}
+	}
+
+	return nil
+}
+
+// writeFieldMatchFunctions adds a `<Field>` function for each field name, matching
+// nodes of any kind with a child in the field, e.g. `MatchCall(Function(...))`.
+//
+// Field names often match kinds, such as the `attribute` field and kind in Python, and
+// the struct keeps its name, so the function is renamed through the package names
+// (`Attribute_`). The `<Struct>With<Field>` functions never clash, so they're the
+// stable alternative. This is written after every other helper has been named, so a
+// field can't take a helper's name either.
+func writeFieldMatchFunctions(file *jen.File, nodeTypes nodeTypes, nm *nodeMap) {
+	seen := map[string]bool{}
+	for _, nodeType := range nodeTypes {
+		if !nodeType.Named {
+			continue
+		}
+		for fieldName := range nodeType.Fields.FromOldest() {
+			if seen[fieldName] {
+				continue
+			}
+			seen[fieldName] = true
+
+			name := nm.names.getFieldMatcher(createExportedName(fieldName))
+			writeDocComment(
+				file,
+				fmt.Sprintf("%s matches nodes of any kind with a child in the `%s` field that matches the given matcher. If the field holds multiple nodes, any of them can match. Pass it to a `Match` function to check the kind too.", name, fieldName),
+			)
+			file.Func().Id(name).Params(jen.Id("matcher").Qual(runtimePackage, "Matcher")).Qual(runtimePackage, "Matcher").Block(
+				jen.Return(jen.Qual(runtimePackage, "InField").Call(jen.Lit(fieldName), jen.Id("matcher"))),
+			)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package gent
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// ResolveModuleNodeTypes finds the node-types.json file of a grammar distributed as a
+// Go module, e.g. `github.com/tree-sitter/tree-sitter-python`. The module is resolved
+// by the go command in the working directory, using the version required by go.mod,
+// and read from the module cache. A version can be given explicitly with an
+// `@version` suffix, which is read from the module cache directly if it's there.
+//
+// Package paths inside a module, such as `.../tree-sitter-python/bindings/go`, are
+// resolved to the module containing them. The user's GOFLAGS and GOPROXY apply, so
+// go.mod is never rewritten unless they allow it, and vendored modules are found in
+// the module cache by their vendored version.
+func ResolveModuleNodeTypes(module string) (string, error) {
+	modulePath, version, _ := strings.Cut(module, "@")
+
+	dir, err := resolveModuleDir(modulePath, version)
+	if err != nil {
+		return "", err
+	}
+
+	nodeTypesPath := filepath.Join(dir, "src", "node-types.json")
+	if _, err := os.Stat(nodeTypesPath); err == nil {
+		return nodeTypesPath, nil
+	}
+
+	// Some modules contain several grammars, e.g. `typescript/src` and `tsx/src`
+	matches, err := filepath.Glob(filepath.Join(dir, "*", "src", "node-types.json"))
+	if err != nil {
+		return "", fmt.Errorf("Failed to search %s for node-types.json: %w", dir, err)
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("Failed to find src/node-types.json in module %s at %s", modulePath, dir)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("Found multiple node-types.json files in module %s, pass one of them as a path instead: %v", modulePath, matches)
+	}
+}
+
+// goModule is the part of the go command's JSON description of a module gent uses.
+type goModule struct {
+	Path    string
+	Version string
+	Dir     string
+}
+
+// resolveModuleDir returns the directory of the module with the given path, or of the
+// module containing the package with the given path.
+func resolveModuleDir(importPath string, version string) (string, error) {
+	query := importPath
+	if version != "" {
+		query += "@" + version
+	}
+
+	// An exact version that's been downloaded doesn't need the go command, which can't
+	// query versions when modules are vendored
+	for candidate := importPath; version != "" && strings.Contains(candidate, "/"); candidate = path.Dir(candidate) {
+		if dir, ok := moduleCacheDir(candidate, version); ok {
+			return dir, nil
+		}
+	}
+
+	module, err := goListModule(importPath, version)
+	if err != nil && version == "" {
+		// The go command resolves packages to their module, taking replacements and
+		// vendoring into account
+		var pkg struct {
+			Module *goModule
+		}
+		if goList(&pkg, "-find", "-json", importPath) == nil && pkg.Module != nil {
+			module, err = *pkg.Module, nil
+		}
+	}
+	// Packages can't be listed at a version, so try each parent of the path instead
+	for parent := path.Dir(importPath); err != nil && version != "" && strings.Contains(parent, "/"); parent = path.Dir(parent) {
+		if parentModule, parentErr := goListModule(parent, version); parentErr == nil {
+			module, err = parentModule, nil
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("Failed to find module %s, which must be required by go.mod or given a version: %w", query, err)
+	}
+
+	if module.Dir != "" {
+		return module.Dir, nil
+	}
+	// Vendored modules have no directory, but their version can be in the module cache
+	if module.Version != "" {
+		if dir, ok := moduleCacheDir(module.Path, module.Version); ok {
+			return dir, nil
+		}
+	}
+	download := module.Path
+	if module.Version != "" {
+		download += "@" + module.Version
+	}
+	return "", fmt.Errorf("Failed to find module %s: it isn't in the module cache, download it with `go mod download %s`", query, download)
+}
+
+// goListModule asks the go command for the description of a module.
+func goListModule(modulePath string, version string) (goModule, error) {
+	query := modulePath
+	if version != "" {
+		query += "@" + version
+	}
+	var module goModule
+	err := goList(&module, "-m", "-json", query)
+	return module, err
+}
+
+// goList runs `go list` with the given arguments in the user's environment, and decodes
+// its JSON output into v.
+func goList(v any, args ...string) error {
+	output, err := exec.Command("go", append([]string{"list"}, args...)...).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return errors.New(string(bytes.TrimSpace(exitErr.Stderr)))
+		}
+		return err
+	}
+	if err := json.Unmarshal(output, v); err != nil {
+		return fmt.Errorf("Failed to parse the output of go list %s: %w", strings.Join(args, " "), err)
+	}
+	return nil
+}
+
+// moduleCacheDir returns the directory of a module version in the module cache, if
+// it's been downloaded.
+func moduleCacheDir(modulePath string, version string) (string, bool) {
+	output, err := exec.Command("go", "env", "GOMODCACHE").Output()
+	if err != nil {
+		return "", false
+	}
+	cache := string(bytes.TrimSpace(output))
+	if cache == "" {
+		return "", false
+	}
+	dir := filepath.Join(cache, filepath.FromSlash(escapeModulePath(modulePath))+"@"+escapeModulePath(version))
+	info, err := os.Stat(dir)
+	return dir, err == nil && info.IsDir()
+}
+
+// escapeModulePath escapes upper case letters in a module path or version the way the
+// module cache does, e.g. `github.com/Foo` becomes `github.com/!foo`.
+func escapeModulePath(s string) string {
+	builder := strings.Builder{}
+	for _, r := range s {
+		if unicode.IsUpper(r) {
+			builder.WriteRune('!')
+			r = unicode.ToLower(r)
+		}
+		builder.WriteRune(r)
+	}
+	return builder.String()
+}
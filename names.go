@@ -0,0 +1,158 @@
+package gent
+
+import (
+	"fmt"
+	"slices"
+)
+
+// packageNames keeps track of the package-level names in the generated code, so that
+// the declarations gent adds alongside the node types, such as `Wrap` and `AnyNode`,
+// can't clash with the ones derived from node kinds.
+//
+// Node kinds can produce any exported name, e.g. a `wrap` kind creates a `Wrap` struct
+// and SQL grammars have a `select` kind. Names derived from kinds are the API, so they
+// never change. Instead, a helper whose name is taken gets trailing underscores until
+// it's unique, the same way createPrivateName handles keywords.
+type packageNames struct {
+	// Names declared for node kinds, mapped to a description of what declares them
+	taken map[string]string
+	// Names handed out to helpers, keyed by their usual name. Field matchers have keys of
+	// their own, as they can share a usual name with another helper.
+	helpers map[string]string
+}
+
+func newPackageNames() *packageNames {
+	return &packageNames{
+		taken:   map[string]string{},
+		helpers: map[string]string{},
+	}
+}
+
+// reserve records a name derived from a node kind. Neither of two kinds declaring the
+// same name can be renamed, so that's an error.
+func (p *packageNames) reserve(name string, owner string) error {
+	if existing, ok := p.taken[name]; ok && existing != owner {
+		return fmt.Errorf("%s declares %s, which is already declared by %s", owner, name, existing)
+	}
+	p.taken[name] = owner
+	return nil
+}
+
+// get returns the name of the helper usually called name.
+func (p *packageNames) get(name string) string {
+	return p.assign(name, name, singleDeclName)
+}
+
+// getStruct returns the name of the helper struct usually called name. The names
+// derived from it, such as its constructor, are taken into account too.
+func (p *packageNames) getStruct(name string) string {
+	return p.assign(name, name, structDeclNames)
+}
+
+// getFieldMatcher returns the name of the function matching nodes by a child in the
+// field usually called name. It's kept apart from helpers with the same usual name, as
+// fields can be called anything, e.g. `select`.
+func (p *packageNames) getFieldMatcher(name string) string {
+	return p.assign("field "+name, name, singleDeclName)
+}
+
+// assign hands out the first of name, name_, name__ and so on whose declarations,
+// as returned by decls, are all free. The name handed out is remembered by key.
+func (p *packageNames) assign(key string, name string, decls func(name string) []string) string {
+	if assigned, ok := p.helpers[key]; ok {
+		return assigned
+	}
+	assigned := name
+	for slices.ContainsFunc(decls(assigned), p.isTaken) {
+		assigned += "_"
+	}
+	for _, decl := range decls(assigned) {
+		p.taken[decl] = "gent"
+	}
+	p.helpers[key] = assigned
+	return assigned
+}
+
+func singleDeclName(name string) []string {
+	return []string{name}
+}
+
+func (p *packageNames) isTaken(name string) bool {
+	_, ok := p.taken[name]
+	return ok
+}
+
+// structDeclNames returns the package-level names declared for a struct, other than
+// the methods on it.
+func structDeclNames(structName string) []string {
+	return []string{
+		structName,
+		constructorName(structName),
+		syntaxKindConstName(structName),
+		kindSetVarName(structName),
+		kindIDVarName(structName),
+		matchFuncName(structName),
+	}
+}
+
+// syntaxKindConstName returns the name of the `SyntaxKind` constant for the given
+// struct.
+func syntaxKindConstName(structName string) string {
+	return "SyntaxKind_" + structName
+}
+
+// reserveKindNames reserves the names derived from the node kinds and their fields, so
+// that helpers can be named around them. It fails if two kinds declare the same name.
+func (nm *nodeMap) reserveKindNames(nodeTypes nodeTypes) error {
+	reserveStruct := func(structName string, owner string) error {
+		for _, name := range structDeclNames(structName) {
+			if err := nm.names.reserve(name, owner); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for tsKind, structName := range nm.namedExported.FromOldest() {
+		if err := reserveStruct(structName, fmt.Sprintf("kind %q", tsKind)); err != nil {
+			return err
+		}
+	}
+	for tsKind, structName := range nm.unnamedExported.FromOldest() {
+		if err := reserveStruct(structName, fmt.Sprintf("unnamed kind %q", tsKind)); err != nil {
+			return err
+		}
+	}
+	for tsKind, supertype := range nm.supertypes.FromOldest() {
+		if err := reserveStruct(supertype.name, fmt.Sprintf("supertype %q", tsKind)); err != nil {
+			return err
+		}
+	}
+	for tsKinds, unionType := range nm.unionTypes.FromOldest() {
+		if err := reserveStruct(unionType.name, fmt.Sprintf("the union of %q", tsKinds)); err != nil {
+			return err
+		}
+	}
+	for tsKind, structName := range nm.unknown.FromOldest() {
+		if err := reserveStruct(structName, fmt.Sprintf("undeclared kind %q", tsKind)); err != nil {
+			return err
+		}
+	}
+
+	for _, nodeType := range nodeTypes {
+		structName, _ := nm.getStructName(nodeType.Type, nodeType.Named)
+		for name := range nodeType.Fields.FromOldest() {
+			if err := nm.names.reserve(fieldIDVarName(name), fmt.Sprintf("field %q", name)); err != nil {
+				return err
+			}
+			if !nodeType.Named {
+				continue
+			}
+			owner := fmt.Sprintf("field %q of kind %q", name, nodeType.Type)
+			if err := nm.names.reserve(withFuncName(structName, name), owner); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
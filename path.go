@@ -0,0 +1,43 @@
+package gent
+
+import (
+	"github.com/dave/jennifer/jen"
+)
+
+// writePathOfFunction adds the `PathOf` function, which returns a node's path from the
+// root of its tree. It's a function rather than a method so that fields named `path`
+// keep their accessor names.
+func writePathOfFunction(file *jen.File, nm *nodeMap) {
+	pathOfName := nm.names.get("PathOf")
+	writeDocComment(
+		file,
+		pathOfName+" returns the path of the node from the root of its tree, which stays the same when the source is reformatted or comments are added or removed. It can be resolved back to the node with `"+nm.names.get("Resolve")+"`.",
+	)
+	file.Func().Id(pathOfName).
+		Params(jen.Id("node").Id(nm.names.get("TypedNode"))).
+		Params(jen.Qual(runtimePackage, "Path"), jen.Error()).
+		Block(jen.Return(jen.Qual(runtimePackage, "NodePath").Call(jen.Id(nm.names.get("Grammar")), jen.Id("node").Dot("AsNode").Call())))
+}
+
+// writeResolveFunction adds the `Resolve` function, which returns the typed node at a
+// path.
+func writeResolveFunction(file *jen.File, nm *nodeMap) {
+	resolveName := nm.names.get("Resolve")
+	writeDocComment(
+		file,
+		resolveName+" returns the typed node at the path from the given root, as returned by `"+nm.names.get("PathOf")+"`.",
+	)
+	file.Func().Id(resolveName).
+		Params(
+			jen.Id("root").Op("*").Qual("github.com/tree-sitter/go-tree-sitter", "Node"),
+			jen.Id("path").Qual(runtimePackage, "Path"),
+		).
+		Params(jen.Id(nm.names.get("TypedNode")), jen.Error()).
+		Block(
+			jen.List(jen.Id("node"), jen.Err()).Op(":=").Qual(runtimePackage, "ResolvePath").Call(jen.Id("root"), jen.Id("path")),
+			jen.If(jen.Err().Op("!=").Nil()).Block(
+				jen.Return(jen.Nil(), jen.Err()),
+			),
+			jen.Return(jen.Id(nm.names.get("Wrap")).Call(jen.Id("node"))),
+		)
+}
@@ -0,0 +1,169 @@
+package gent
+
+import (
+	"fmt"
+
+	"github.com/dave/jennifer/jen"
+)
+
+// fieldIDVarName returns the name of the package-level variable holding the ID of the
+// field with the given Tree-sitter name.
+func fieldIDVarName(tsFieldName string) string {
+	return "fieldID_" + createExportedName(tsFieldName)
+}
+
+// kindIDVarName returns the name of the package-level variable holding the ID of the
+// kind with the given struct name.
+func kindIDVarName(structName string) string {
+	return "kindID_" + structName
+}
+
+// writeFieldIDs adds a variable holding the ID of each field name used in the node
+// types. Field IDs are shared by every kind in a language.
+//
+// If the package is bound to a language, the IDs are resolved from it when the package
+// is initialised. Otherwise, they're resolved from the first node they're used with.
+func writeFieldIDs(file *jen.File, nodeTypes nodeTypes, nm *nodeMap, bound bool) {
+	boundLanguageName := nm.names.get(boundLanguageVarName)
+	defs := []jen.Code{}
+	seen := map[string]bool{}
+	for _, nodeType := range nodeTypes {
+		for name := range nodeType.Fields.FromOldest() {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			value := jen.Qual(runtimePackage, "NewFieldID").Call(jen.Lit(name))
+			if bound {
+				value = jen.Id(boundLanguageName).Dot("FieldIdForName").Call(jen.Lit(name))
+			}
+			defs = append(defs, jen.Id(fieldIDVarName(name)).Op("=").Add(value))
+		}
+	}
+
+	if bound {
+		file.Comment("Field IDs, resolved from the language once, so accessors can compare integers")
+		file.Comment("instead of looking up field names.")
+	} else {
+		file.Comment("Field IDs used by the `Append` methods. They're resolved from the language once.")
+	}
+	file.Var().Defs(defs...)
+}
+
+// writeKindIDs adds a variable holding the ID of each concrete kind, including the ones
+// node-types.json uses but never declares, resolved from the bound language when the
+// package is initialised.
+func writeKindIDs(file *jen.File, nodeTypes nodeTypes, nm *nodeMap) {
+	boundLanguageName := nm.names.get(boundLanguageVarName)
+	defs := []jen.Code{}
+	for _, nodeType := range nodeTypes {
+		if nodeType.Subtypes != nil {
+			continue
+		}
+		structName, _ := nm.getStructName(nodeType.Type, nodeType.Named)
+		defs = append(defs, jen.Id(kindIDVarName(structName)).Op("=").Id(boundLanguageName).Dot("IdForNodeKind").Call(
+			jen.Lit(nodeType.Type),
+			jen.Lit(nodeType.Named),
+		))
+	}
+	for tsKind, structName := range nm.unknown.FromOldest() {
+		defs = append(defs, jen.Id(kindIDVarName(structName)).Op("=").Id(boundLanguageName).Dot("IdForNodeKind").Call(
+			jen.Lit(tsKind),
+			jen.Lit(nm.unknownNamed[tsKind]),
+		))
+	}
+
+	file.Comment("Kind IDs, resolved from the language once, so constructors and union accessors")
+	file.Comment("can compare integers instead of kind names.")
+	file.Var().Defs(defs...)
+}
+
+// writeAppendMethods adds an `Append<Field>` method for each field that can hold
+// multiple nodes, and `AppendTypedChildren` if the children can. They walk the
+// children with a pooled cursor and append to a caller-supplied buffer, rather than
+// allocating slices of nodes and wrappers.
+func writeAppendMethods(file *jen.File, stDef structDef, structMethodIdentifier string) {
+	receiverNode := jen.Op("&").Id(structMethodIdentifier).Dot("Node")
+
+	// fieldID is nil for `AppendTypedChildren`, which doesn't filter by field
+	writeMethod := func(methodName string, returnType string, doc string, fieldID jen.Code, namedOnly bool) {
+		walkDoc := "It walks the children with a pooled cursor, filtering them by field ID."
+		if fieldID == nil {
+			walkDoc = "It walks the children with a pooled cursor."
+		}
+		writeDocComment(
+			file,
+			doc,
+			walkDoc+" The only allocations are the buffer, if it needs to grow, and the node handles created by the Tree-sitter bindings. Pass `buf[:0]` to reuse a buffer between calls.",
+		)
+
+		loopBody := []jen.Code{}
+		if fieldID != nil {
+			loopBody = append(loopBody, jen.If(jen.Id("cursor").Dot("FieldId").Call().Op("!=").Id("id")).Block(jen.Continue()))
+		}
+		loopBody = append(loopBody, jen.Id("child").Op(":=").Id("cursor").Dot("Node").Call())
+		if namedOnly {
+			loopBody = append(loopBody, jen.If(
+				jen.Op("!").Id("child").Dot("IsNamed").Call().Op("||").Id("child").Dot("IsExtra").Call(),
+			).Block(jen.Continue()))
+		}
+		loopBody = append(loopBody, jen.Id("buf").Op("=").Append(
+			jen.Id("buf"),
+			jen.Id(returnType).Values(jen.Dict{jen.Id("Node"): jen.Op("*").Id("child")}),
+		))
+
+		body := []jen.Code{}
+		if fieldID != nil {
+			// The field isn't in the language if its ID is zero, which would match every
+			// child without a field. This applies to IDs resolved from a bound language too.
+			body = append(body,
+				jen.Id("id").Op(":=").Add(fieldID),
+				jen.If(jen.Id("id").Op("==").Lit(0)).Block(jen.Return(jen.Id("buf"))),
+			)
+		}
+		body = append(body,
+			jen.Id("cursor").Op(":=").Qual(runtimePackage, "AcquireCursor").Call(receiverNode.Clone()),
+			jen.Defer().Qual(runtimePackage, "ReleaseCursor").Call(jen.Id("cursor")),
+			jen.For(
+				jen.Id("ok").Op(":=").Id("cursor").Dot("GotoFirstChild").Call(),
+				jen.Id("ok"),
+				jen.Id("ok").Op("=").Id("cursor").Dot("GotoNextSibling").Call(),
+			).Block(loopBody...),
+			jen.Return(jen.Id("buf")),
+		)
+		file.Func().
+			Parens(jen.Id(structMethodIdentifier).Op("*").Id(stDef.name)).
+			Id(methodName).
+			Params(jen.Id("buf").Index().Id(returnType)).
+			Index().Id(returnType).
+			Block(body...)
+	}
+
+	for _, fieldDef := range stDef.methods {
+		if !fieldDef.array {
+			continue
+		}
+		methodName := "Append" + accessorName(fieldDef.methodName)
+		fieldID := jen.Id(fieldIDVarName(fieldDef.tsFieldName))
+		if !stDef.resolvedIDs {
+			fieldID = fieldID.Dot("ID").Call(receiverNode.Clone())
+		}
+		writeMethod(
+			methodName,
+			fieldDef.returnType,
+			fmt.Sprintf("%s appends the nodes in the %q field to buf and returns the extended buffer.", methodName, fieldDef.tsFieldName),
+			fieldID,
+			false,
+		)
+	}
+
+	if stDef.childrenMethodDef != nil && stDef.childrenMethodDef.array {
+		writeMethod(
+			"AppendTypedChildren",
+			stDef.childrenMethodDef.returnType,
+			"AppendTypedChildren appends the named children of the node that aren't extras to buf and returns the extended buffer, like `TypedChildren`.",
+			nil,
+			true,
+		)
+	}
+}
@@ -0,0 +1,134 @@
+package gent
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// protobufBackend renders the node type model as a proto3 file, with a message per
+// concrete kind and supertypes and unions represented as `oneof`s.
+//
+// Unlike the JSON output of the generated Go types, messages have no `kind`, as it's
+// implied by the message type, and each Tree-sitter field is a field of the message
+// itself rather than an entry in a `fields` map.
+type protobufBackend struct {
+	options GeneratorOptions
+}
+
+func (b *protobufBackend) render(nodeTypes nodeTypes, nm *nodeMap) (string, error) {
+	packageName := "node_types"
+	if b.options.PackageName != "" {
+		packageName = b.options.PackageName
+	}
+
+	output := &strings.Builder{}
+	fmt.Fprintf(output, "syntax = \"proto3\";\n\npackage %s;\n\n", packageName)
+	fmt.Fprintf(output, "message %s {\n  uint32 row = 1;\n  uint32 column = 2;\n}\n\n", pointTypeName)
+	fmt.Fprintf(output, "message %s {\n  uint32 start_byte = 1;\n  uint32 end_byte = 2;\n  %s start_point = 3;\n  %s end_point = 4;\n}\n", rangeTypeName, pointTypeName, pointTypeName)
+
+	for _, nodeType := range nodeTypes {
+		if nodeType.Subtypes != nil {
+			continue
+		}
+		structName, _ := nm.getStructName(nodeType.Type, nodeType.Named)
+		if err := writeProtoNodeMessage(output, structName, nodeType, nm); err != nil {
+			return "", fmt.Errorf("Failed to write message for %s: %w", nodeType.Type, err)
+		}
+	}
+
+	for _, supertype := range nm.supertypes.FromOldest() {
+		if err := writeProtoUnionMessage(output, supertype, nm); err != nil {
+			return "", fmt.Errorf("Failed to write message for %s: %w", supertype.name, err)
+		}
+	}
+
+	for _, unionType := range nm.unionTypes.FromOldest() {
+		if err := writeProtoUnionMessage(output, unionType, nm); err != nil {
+			return "", fmt.Errorf("Failed to write message for %s: %w", unionType.name, err)
+		}
+	}
+
+	for tsKind, structName := range nm.unknown.FromOldest() {
+		if err := writeProtoNodeMessage(output, structName, nodeType{Type: tsKind}, nm); err != nil {
+			return "", fmt.Errorf("Failed to write message for %s: %w", tsKind, err)
+		}
+	}
+
+	return output.String(), nil
+}
+
+func writeProtoNodeMessage(output *strings.Builder, structName string, nodeType nodeType, nm *nodeMap) error {
+	fmt.Fprintf(output, "\n// Tree-sitter kind: %q\nmessage %s {\n", nodeType.Type, protoMessageName(structName))
+	fmt.Fprintf(output, "  %s range = 1;\n  optional string text = 2;\n", rangeTypeName)
+
+	fieldNumber := 3
+	for name, field := range nodeType.Fields.FromOldest() {
+		typeName, ok := nm.getTypeName(field.Types)
+		if !ok {
+			return fmt.Errorf("Failed to find type name for field %s", name)
+		}
+		repeated := ""
+		if field.Multiple {
+			repeated = "repeated "
+		}
+		fmt.Fprintf(output, "  %s%s %s = %d;\n", repeated, protoMessageName(typeName), protoFieldName(name), fieldNumber)
+		fieldNumber++
+	}
+
+	if len(nodeType.Children.Types) > 0 {
+		typeName, ok := nm.getTypeName(nodeType.Children.Types)
+		if !ok {
+			return fmt.Errorf("Failed to find type name for children")
+		}
+		fmt.Fprintf(output, "  repeated %s children = %d;\n", protoMessageName(typeName), fieldNumber)
+	}
+
+	output.WriteString("}\n")
+	return nil
+}
+
+func writeProtoUnionMessage(output *strings.Builder, unionType unionType, nm *nodeMap) error {
+	fmt.Fprintf(output, "\nmessage %s {\n  oneof node {\n", protoMessageName(unionType.name))
+	for i, member := range unionType.members {
+		structName, ok := nm.getStructName(member.Type, member.Named)
+		if !ok {
+			return fmt.Errorf("Failed to find struct name for %s", member.Type)
+		}
+		fmt.Fprintf(output, "    %s %s = %d;\n", protoMessageName(structName), toSnakeCase(structName), i+1)
+	}
+	output.WriteString("  }\n}\n")
+	return nil
+}
+
+// protoFieldName returns the name of the message field for a Tree-sitter field,
+// avoiding the names of the fields every node message has.
+func protoFieldName(name string) string {
+	switch name {
+	case "range", "text", "children":
+		return name + "_field"
+	}
+	return name
+}
+
+// protoMessageName converts a struct name into a message name. Private union types
+// are capitalised so that all messages follow the same convention.
+func protoMessageName(structName string) string {
+	return upperFirst(structName)
+}
+
+// toSnakeCase converts a struct name such as `Unnamed_AddEq` into a snake_case name
+// such as `unnamed_add_eq`.
+func toSnakeCase(s string) string {
+	output := &strings.Builder{}
+	for i, r := range s {
+		if unicode.IsUpper(r) {
+			if i > 0 && s[i-1] != '_' {
+				output.WriteRune('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		output.WriteRune(r)
+	}
+	return output.String()
+}
@@ -0,0 +1,63 @@
+package runtime
+
+import (
+	"fmt"
+	"sync"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// Binding is a generated package bound to the Go bindings of its grammar. Packages
+// generated with a language register their binding when they're initialised, so that
+// tools such as `gent corpus` can parse and check sources with a package without
+// knowing its name.
+type Binding struct {
+	Language *tree_sitter.Language
+	Grammar  *Grammar
+	// Wrap creates the concrete typed node for a node, as the package's `Wrap`
+	// function does.
+	Wrap func(node *tree_sitter.Node) (TypedNode, error)
+}
+
+var (
+	bindingsMu sync.RWMutex
+	bindings   []*Binding
+)
+
+// RegisterBinding adds a binding to the ones returned by LookupBinding.
+func RegisterBinding(binding *Binding) {
+	bindingsMu.Lock()
+	defer bindingsMu.Unlock()
+	bindings = append(bindings, binding)
+}
+
+// LookupBinding returns the registered binding, returning an error if there isn't
+// exactly one.
+func LookupBinding() (*Binding, error) {
+	bindingsMu.RLock()
+	defer bindingsMu.RUnlock()
+	switch len(bindings) {
+	case 0:
+		return nil, fmt.Errorf("No generated package is bound to a language, generate one with `--language`")
+	case 1:
+		return bindings[0], nil
+	default:
+		return nil, fmt.Errorf("Found %d generated packages bound to a language, expected 1", len(bindings))
+	}
+}
+
+// NewParser creates a parser for the binding's language. The caller must close it.
+func (b *Binding) NewParser() (*tree_sitter.Parser, error) {
+	parser := tree_sitter.NewParser()
+	if err := parser.SetLanguage(b.Language); err != nil {
+		parser.Close()
+		return nil, fmt.Errorf("Failed to set language: %w", err)
+	}
+	return parser, nil
+}
+
+// CheckTree checks the tree rooted at the given node with the binding's grammar and
+// typed nodes. See the package-level CheckTree.
+func (b *Binding) CheckTree(root *tree_sitter.Node) []Problem {
+	return CheckTree(b.Grammar, root, b.Wrap)
+}
@@ -0,0 +1,223 @@
+package runtime
+
+import (
+	"fmt"
+	"reflect"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// Problem is a node in a tree that doesn't fit the generated types.
+type Problem struct {
+	// The kind of the node with the problem.
+	Kind SyntaxKind
+	// The field the problem relates to, if any.
+	Field string
+	// The position of the node. Zero for nodes from S-expressions.
+	Range   tree_sitter.Range
+	Message string
+}
+
+func (p Problem) String() string {
+	if p.Range == (tree_sitter.Range{}) {
+		return fmt.Sprintf("%s: %s", p.Kind, p.Message)
+	}
+	return fmt.Sprintf("%s at %s: %s", p.Kind, formatPoint(p.Range.StartPoint), p.Message)
+}
+
+// CheckTree checks every node in the tree rooted at the given node against the
+// grammar, through the generated types. Each node is passed to wrap, which should
+// create its typed node, and then each of its field accessors and its `TypedChildren`
+// or `TypedChild` accessor is called. Problems are reported when an accessor fails,
+// returns a different number of nodes than the field holds, or returns a node that
+// its type's constructor rejects.
+//
+// Fields and children are also checked against the cardinality declared in the
+// grammar. Nodes with syntax errors among their children aren't checked, as the
+// parser can put anything there.
+func CheckTree(grammar *Grammar, root *tree_sitter.Node, wrap func(*tree_sitter.Node) (TypedNode, error)) []Problem {
+	problems := []Problem{}
+	cursor := root.Walk()
+	defer cursor.Close()
+	// Accessors are given their own cursor, as cursor is in use by the walk
+	accessorCursor := root.Walk()
+	defer accessorCursor.Close()
+
+	var check func(node *tree_sitter.Node)
+	check = func(node *tree_sitter.Node) {
+		if node.IsError() || node.IsMissing() {
+			return
+		}
+
+		problem := func(field string, format string, args ...any) {
+			problems = append(problems, Problem{
+				Kind:    node.Kind(),
+				Field:   field,
+				Range:   node.Range(),
+				Message: fmt.Sprintf(format, args...),
+			})
+		}
+
+		info, ok := grammar.Lookup(node.Kind(), node.IsNamed())
+		if !ok {
+			problem("", "kind isn't declared in node-types.json")
+			return
+		}
+		typed, err := wrap(node)
+		if err != nil {
+			problem("", "failed to wrap node: %v", err)
+		}
+
+		fieldCounts := map[string]int{}
+		childCount := 0
+		children := []tree_sitter.Node{}
+		broken := false
+		cursor.Reset(*node)
+		for ok := cursor.GotoFirstChild(); ok; ok = cursor.GotoNextSibling() {
+			child := cursor.Node()
+			children = append(children, *child)
+			if child.IsError() || child.IsMissing() {
+				broken = true
+				continue
+			}
+			if child.IsExtra() {
+				continue
+			}
+
+			fieldName := cursor.FieldName()
+			if fieldName == "" {
+				// Unnamed tokens outside of fields are never typed
+				if !child.IsNamed() {
+					continue
+				}
+				childCount++
+				if info.Children == nil {
+					problem("", "unexpected child of kind %s", child.Kind())
+				}
+				continue
+			}
+
+			fieldCounts[fieldName]++
+			if _, ok := info.Field(fieldName); !ok {
+				problem(fieldName, "field isn't declared in node-types.json")
+			}
+		}
+
+		for _, field := range info.Fields {
+			checkCardinality(field, fieldCounts[field.Name], problem)
+			if typed != nil && !broken {
+				checkAccessor(typed, field, fieldCounts[field.Name], accessorCursor, problem)
+			}
+		}
+		if info.Children != nil {
+			checkCardinality(*info.Children, childCount, problem)
+			if typed != nil && !broken {
+				checkAccessor(typed, *info.Children, childCount, accessorCursor, problem)
+			}
+		}
+
+		for i := range children {
+			check(&children[i])
+		}
+	}
+
+	check(root)
+	return problems
+}
+
+// checkAccessor calls the accessor of a field on the typed node, and reports a
+// problem if it fails, returns a different number of nodes than count, or returns a
+// node its type's constructor rejects.
+func checkAccessor(
+	typed TypedNode,
+	field FieldInfo,
+	count int,
+	cursor *tree_sitter.TreeCursor,
+	problem func(field string, format string, args ...any),
+) {
+	method := reflect.ValueOf(typed).MethodByName(field.Accessor)
+	if !method.IsValid() {
+		problem(field.Name, "%T has no %s accessor", typed, field.Accessor)
+		return
+	}
+	args := []reflect.Value{}
+	if method.Type().NumIn() == 1 {
+		args = append(args, reflect.ValueOf(cursor))
+	}
+	results := method.Call(args)
+	if len(results) == 2 {
+		if err, _ := results[1].Interface().(error); err != nil {
+			// Empty fields are reported by the cardinality check
+			if count > 0 {
+				problem(field.Name, "%s failed: %v", field.Accessor, err)
+			}
+			return
+		}
+	}
+
+	nodes := []reflect.Value{}
+	if field.Multiple {
+		for i := range results[0].Len() {
+			nodes = append(nodes, results[0].Index(i))
+		}
+	} else {
+		nodes = append(nodes, results[0])
+	}
+	// Accessors of single fields return the first node if there are several, which
+	// the cardinality check reports
+	expected := count
+	if !field.Multiple {
+		expected = min(count, 1)
+	}
+	if len(nodes) != expected {
+		problem(field.Name, "%s returned %d nodes, but the node has %d", field.Accessor, len(nodes), count)
+	}
+	for _, value := range nodes {
+		checkAccessorResult(value, field, problem)
+	}
+}
+
+// checkAccessorResult reports a problem if the constructor of the node's type rejects
+// it, e.g. because the accessor returned a node of a kind its type doesn't allow.
+func checkAccessorResult(value reflect.Value, field FieldInfo, problem func(field string, format string, args ...any)) {
+	// Accessors return pointers for fields, and values for children
+	if value.Kind() != reflect.Pointer {
+		pointer := reflect.New(value.Type())
+		pointer.Elem().Set(value)
+		value = pointer
+	}
+	typed, ok := value.Interface().(TypedNode)
+	if !ok {
+		problem(field.Name, "%s returned %v, which isn't a typed node", field.Accessor, value.Type())
+		return
+	}
+
+	node := typed.AsNode()
+	constructor, ok := LookupConstructor(value.Type().Elem())
+	if !ok {
+		// Types without a constructor, such as kinds node-types.json never declares,
+		// are checked against the grammar instead
+		if !field.Allows(node.Kind()) {
+			problem(field.Name, "%s returned a node of kind %s, which isn't one of %v", field.Accessor, node.Kind(), field.Kinds)
+		}
+		return
+	}
+	if _, err := constructor(node); err != nil {
+		problem(field.Name, "%s returned a node of kind %s, which %v can't hold: %v", field.Accessor, node.Kind(), value.Type().Elem(), err)
+	}
+}
+
+// checkCardinality reports a problem if the number of nodes in a field doesn't match
+// its declaration.
+func checkCardinality(field FieldInfo, count int, problem func(field string, format string, args ...any)) {
+	name := field.Name
+	if name == "" {
+		name = "children"
+	}
+	if field.Required && count == 0 {
+		problem(field.Name, "required %s is missing", name)
+	}
+	if !field.Multiple && count > 1 {
+		problem(field.Name, "%s can hold one node, but has %d", name, count)
+	}
+}
@@ -0,0 +1,57 @@
+package runtime
+
+import (
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// Wrapper is satisfied by every generated struct, as they only embed a node. The
+// helpers below use it to create typed nodes, so that generated code can delegate to
+// them instead of inlining the same logic in every accessor.
+type Wrapper interface {
+	~struct{ tree_sitter.Node }
+}
+
+// KindSet is a precomputed set of kinds, so that checking a node's kind doesn't need to
+// allocate or search a slice.
+type KindSet struct {
+	kinds []SyntaxKind
+	set   map[SyntaxKind]struct{}
+}
+
+// NewKindSet creates a set of the given kinds.
+func NewKindSet(kinds ...SyntaxKind) *KindSet {
+	set := make(map[SyntaxKind]struct{}, len(kinds))
+	for _, kind := range kinds {
+		set[kind] = struct{}{}
+	}
+	return &KindSet{kinds: kinds, set: set}
+}
+
+// Contains reports whether the kind is in the set.
+func (s *KindSet) Contains(kind SyntaxKind) bool {
+	_, ok := s.set[kind]
+	return ok
+}
+
+// Kinds returns the kinds in the set, in the order they were given.
+func (s *KindSet) Kinds() []SyntaxKind {
+	return s.kinds
+}
+
+// As wraps the node in T, returning an error if it isn't one of the given kinds.
+func As[T Wrapper](node *tree_sitter.Node, kinds *KindSet) (*T, error) {
+	if !kinds.Contains(node.Kind()) {
+		return nil, NewKindMismatchError(kinds.Kinds(), node)
+	}
+	return &T{Node: *node}, nil
+}
+
+// AllChildrenOf returns every child of the node, wrapped in T.
+func AllChildrenOf[T Wrapper](node *tree_sitter.Node, cursor *tree_sitter.TreeCursor) []T {
+	children := node.Children(cursor)
+	output := make([]T, 0, len(children))
+	for _, child := range children {
+		output = append(output, T{Node: child})
+	}
+	return output
+}
@@ -0,0 +1,313 @@
+package runtime
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// CorpusExample is a single test from a Tree-sitter corpus file, as found in a
+// grammar's `test/corpus` directory.
+type CorpusExample struct {
+	Name string
+	// Attributes such as `:skip` and `:error`, without the leading colon.
+	Attributes []string
+	Source     []byte
+	// The expected tree, as an S-expression.
+	Expected string
+}
+
+// ParseCorpus parses the examples from the contents of a corpus file.
+func ParseCorpus(data []byte) ([]CorpusExample, error) {
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	// Header and divider lines can have a suffix to disambiguate them from the source
+	isHeader := func(line string) bool {
+		line = strings.TrimRight(line, " \t")
+		return strings.HasPrefix(line, "===") && !strings.ContainsAny(strings.TrimLeft(line, "="), "= ")
+	}
+	isDivider := func(line string) bool {
+		line = strings.TrimRight(line, " \t")
+		return strings.HasPrefix(line, "---") && !strings.ContainsAny(strings.TrimLeft(line, "-"), "- ")
+	}
+
+	examples := []CorpusExample{}
+	i := 0
+	for i < len(lines) {
+		if !isHeader(lines[i]) {
+			i++
+			continue
+		}
+
+		// Name and attribute lines, up to the closing header line
+		example := CorpusExample{}
+		i++
+		nameLines := []string{}
+		for i < len(lines) && !isHeader(lines[i]) {
+			line := strings.TrimSpace(lines[i])
+			if strings.HasPrefix(line, ":") {
+				example.Attributes = append(example.Attributes, strings.TrimPrefix(line, ":"))
+			} else if line != "" {
+				nameLines = append(nameLines, line)
+			}
+			i++
+		}
+		if i == len(lines) {
+			return nil, fmt.Errorf("Unterminated header for example %q", strings.Join(nameLines, " "))
+		}
+		example.Name = strings.Join(nameLines, " ")
+		i++
+
+		sourceLines := []string{}
+		for i < len(lines) && !isDivider(lines[i]) {
+			sourceLines = append(sourceLines, lines[i])
+			i++
+		}
+		if i == len(lines) {
+			return nil, fmt.Errorf("Missing expected tree for example %q", example.Name)
+		}
+		example.Source = []byte(strings.Trim(strings.Join(sourceLines, "\n"), "\n"))
+		i++
+
+		expectedLines := []string{}
+		for i < len(lines) && !isHeader(lines[i]) {
+			expectedLines = append(expectedLines, lines[i])
+			i++
+		}
+		example.Expected = strings.TrimSpace(strings.Join(expectedLines, "\n"))
+
+		examples = append(examples, example)
+	}
+
+	return examples, nil
+}
+
+// HasAttribute reports whether the example has the given attribute, e.g. `skip`.
+func (e CorpusExample) HasAttribute(attribute string) bool {
+	return slices.Contains(e.Attributes, attribute)
+}
+
+// SExpression is a node in the expected tree of a corpus example.
+type SExpression struct {
+	Kind SyntaxKind
+	// The field this node is in, if the S-expression labels it.
+	Field    string
+	Children []*SExpression
+}
+
+// ParseSExpression parses an S-expression in the format used by Tree-sitter corpus
+// files, e.g. `(module (expression_statement (identifier)))`. Comments starting with
+// `;` are ignored, as are anonymous nodes written as string literals.
+func ParseSExpression(s string) (*SExpression, error) {
+	tokens, err := tokenizeSExpression(s)
+	if err != nil {
+		return nil, err
+	}
+
+	pos := 0
+	var parseNode func(field string) (*SExpression, error)
+	parseNode = func(field string) (*SExpression, error) {
+		if pos >= len(tokens) || tokens[pos] != "(" {
+			return nil, fmt.Errorf("Expected ( at token %d", pos)
+		}
+		pos++
+		if pos >= len(tokens) || tokens[pos] == "(" || tokens[pos] == ")" {
+			return nil, fmt.Errorf("Expected node kind at token %d", pos)
+		}
+		node := &SExpression{Kind: tokens[pos], Field: field}
+		pos++
+
+		for pos < len(tokens) && tokens[pos] != ")" {
+			token := tokens[pos]
+			switch {
+			case token == "(":
+				child, err := parseNode("")
+				if err != nil {
+					return nil, err
+				}
+				node.Children = append(node.Children, child)
+			case strings.HasSuffix(token, ":"):
+				pos++
+				child, err := parseNode(strings.TrimSuffix(token, ":"))
+				if err != nil {
+					return nil, err
+				}
+				node.Children = append(node.Children, child)
+			default:
+				// String literals and bare words, e.g. in `(MISSING ";")`
+				pos++
+			}
+		}
+		if pos >= len(tokens) {
+			return nil, fmt.Errorf("Unterminated node %s", node.Kind)
+		}
+		pos++
+		return node, nil
+	}
+
+	node, err := parseNode("")
+	if err != nil {
+		return nil, err
+	}
+	if pos != len(tokens) {
+		return nil, fmt.Errorf("Unexpected token %q after the root node", tokens[pos])
+	}
+	return node, nil
+}
+
+func tokenizeSExpression(s string) ([]string, error) {
+	tokens := []string{}
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+		case r == ';':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case r == '(' || r == ')':
+			tokens = append(tokens, string(r))
+		case r == '"' || r == '\'':
+			start := i
+			for i++; i < len(runes) && runes[i] != r; i++ {
+				if runes[i] == '\\' {
+					i++
+				}
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("Unterminated string literal")
+			}
+			tokens = append(tokens, string(runes[start:i+1]))
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != '(' && runes[i] != ')' {
+				i++
+			}
+			tokens = append(tokens, string(runes[start:i]))
+			i--
+		}
+	}
+	return tokens, nil
+}
+
+// CheckSExpression checks the expected tree of a corpus example against the grammar.
+// If the S-expression doesn't label any fields, children are only checked against
+// the kinds allowed anywhere in their parent, and required fields aren't checked.
+func CheckSExpression(grammar *Grammar, root *SExpression) []Problem {
+	labelled := false
+	var findLabels func(node *SExpression)
+	findLabels = func(node *SExpression) {
+		labelled = labelled || node.Field != ""
+		for _, child := range node.Children {
+			findLabels(child)
+		}
+	}
+	findLabels(root)
+
+	problems := []Problem{}
+	var check func(node *SExpression)
+	check = func(node *SExpression) {
+		if node.Kind == "ERROR" || node.Kind == "MISSING" || node.Kind == "UNEXPECTED" {
+			return
+		}
+
+		problem := func(field string, format string, args ...any) {
+			problems = append(problems, Problem{
+				Kind:    node.Kind,
+				Field:   field,
+				Message: fmt.Sprintf(format, args...),
+			})
+		}
+
+		info, ok := grammar.Lookup(node.Kind, true)
+		if !ok {
+			problem("", "kind isn't declared in node-types.json")
+			return
+		}
+
+		fieldCounts := map[string]int{}
+		childCount := 0
+		for _, child := range node.Children {
+			if grammar.IsExtra(child.Kind) || child.Kind == "ERROR" || child.Kind == "MISSING" {
+				continue
+			}
+
+			if child.Field != "" {
+				fieldCounts[child.Field]++
+				field, ok := info.Field(child.Field)
+				if !ok {
+					problem(child.Field, "field isn't declared in node-types.json")
+				} else if !field.Allows(child.Kind) {
+					problem(child.Field, "child of kind %s isn't one of %v", child.Kind, field.Kinds)
+				}
+				continue
+			}
+
+			childCount++
+			if info.Children != nil && info.Children.Allows(child.Kind) {
+				continue
+			}
+			// Without labels, the child could be in any of the fields
+			allowedInField := false
+			for _, field := range info.Fields {
+				allowedInField = allowedInField || (!labelled && field.Allows(child.Kind))
+			}
+			if !allowedInField {
+				problem("", "unexpected child of kind %s", child.Kind)
+			}
+		}
+
+		if labelled {
+			for _, field := range info.Fields {
+				// Unnamed tokens aren't included in S-expressions
+				if grammar.hasNamedKind(field) {
+					checkCardinality(field, fieldCounts[field.Name], problem)
+				}
+			}
+			if info.Children != nil {
+				checkCardinality(*info.Children, childCount, problem)
+			}
+		}
+
+		for _, child := range node.Children {
+			check(child)
+		}
+	}
+
+	check(root)
+	return problems
+}
+
+// CorpusResult contains the problems found in a single corpus example.
+type CorpusResult struct {
+	Example  CorpusExample
+	Problems []Problem
+}
+
+// RunCorpus parses the source of every example with the given language and checks
+// the resulting trees using check, which is usually a generated package's
+// `CheckTree` function. Examples with the `skip` attribute are ignored.
+func RunCorpus(language *tree_sitter.Language, examples []CorpusExample, check func(*tree_sitter.Node) []Problem) ([]CorpusResult, error) {
+	parser := tree_sitter.NewParser()
+	defer parser.Close()
+	if err := parser.SetLanguage(language); err != nil {
+		return nil, fmt.Errorf("Failed to set language: %w", err)
+	}
+
+	results := []CorpusResult{}
+	for _, example := range examples {
+		if example.HasAttribute("skip") {
+			continue
+		}
+		tree := parser.Parse(example.Source, nil)
+		results = append(results, CorpusResult{
+			Example:  example,
+			Problems: check(tree.RootNode()),
+		})
+		tree.Close()
+	}
+	return results, nil
+}
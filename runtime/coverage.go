@@ -0,0 +1,211 @@
+package runtime
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// Coverage counts the kinds, fields and field members that occur in a set of trees,
+// and which kinds a visitor handles and reaches.
+type Coverage struct {
+	grammar *Grammar
+	kinds   map[*KindInfo]int
+	fields  map[*FieldInfo]int
+	members map[*FieldInfo]map[SyntaxKind]int
+	// Kinds the visitor has a method for
+	handled map[SyntaxKind]bool
+	// Kinds whose visitor method was called
+	reached map[SyntaxKind]int
+}
+
+// NewCoverage creates an empty coverage for the kinds in the given grammar.
+func NewCoverage(grammar *Grammar) *Coverage {
+	return &Coverage{
+		grammar: grammar,
+		kinds:   map[*KindInfo]int{},
+		fields:  map[*FieldInfo]int{},
+		members: map[*FieldInfo]map[SyntaxKind]int{},
+		handled: map[SyntaxKind]bool{},
+		reached: map[SyntaxKind]int{},
+	}
+}
+
+// AddTree counts every node in the tree rooted at the given node. Nodes of kinds
+// that aren't in the grammar are ignored.
+func (c *Coverage) AddTree(root *tree_sitter.Node) {
+	cursor := root.Walk()
+	defer cursor.Close()
+
+	var add func(node *tree_sitter.Node)
+	add = func(node *tree_sitter.Node) {
+		info, ok := c.grammar.Lookup(node.Kind(), node.IsNamed())
+		if !ok {
+			return
+		}
+		c.kinds[info]++
+
+		children := []tree_sitter.Node{}
+		cursor.Reset(*node)
+		for ok := cursor.GotoFirstChild(); ok; ok = cursor.GotoNextSibling() {
+			child := cursor.Node()
+			children = append(children, *child)
+			if child.IsExtra() {
+				continue
+			}
+
+			var field *FieldInfo
+			if fieldName := cursor.FieldName(); fieldName != "" {
+				field, _ = info.Field(fieldName)
+			} else if child.IsNamed() {
+				field = info.Children
+			}
+			if field == nil || !field.Allows(child.Kind()) {
+				continue
+			}
+			c.fields[field]++
+			if c.members[field] == nil {
+				c.members[field] = map[SyntaxKind]int{}
+			}
+			c.members[field][child.Kind()]++
+		}
+
+		for i := range children {
+			add(&children[i])
+		}
+	}
+
+	add(root)
+}
+
+// Handle records that the visitor has methods for the given kinds.
+func (c *Coverage) Handle(kinds ...SyntaxKind) {
+	for _, kind := range kinds {
+		c.handled[kind] = true
+	}
+}
+
+// Reach records that the visitor's method for the given kind was called.
+func (c *Coverage) Reach(kind SyntaxKind) {
+	c.reached[kind]++
+}
+
+// CoverageEntry is the number of times a kind, field or field member occurred.
+type CoverageEntry struct {
+	// The kind, e.g. `function_definition`, the field, e.g. `function_definition.name`,
+	// or the member, e.g. `function_definition.body: block`. Unnamed kinds are quoted,
+	// and children are written as a field called `children`.
+	Name  string
+	Count int
+}
+
+// CoverageReport lists the occurrences of everything declared in the grammar.
+type CoverageReport struct {
+	Kinds   []CoverageEntry
+	Fields  []CoverageEntry
+	Members []CoverageEntry
+	// Kinds the visitor has a method for, but which were never reached.
+	Unreached []SyntaxKind
+}
+
+// Report returns the occurrences of every kind, field and member of a field that can
+// hold more than one kind, sorted by name.
+func (c *Coverage) Report() CoverageReport {
+	report := CoverageReport{
+		Kinds:     []CoverageEntry{},
+		Fields:    []CoverageEntry{},
+		Members:   []CoverageEntry{},
+		Unreached: []SyntaxKind{},
+	}
+
+	addKind := func(info *KindInfo) {
+		name := info.Kind
+		if !info.Named {
+			name = strconv.Quote(info.Kind)
+		}
+		report.Kinds = append(report.Kinds, CoverageEntry{Name: name, Count: c.kinds[info]})
+
+		fields := []*FieldInfo{}
+		for i := range info.Fields {
+			fields = append(fields, &info.Fields[i])
+		}
+		if info.Children != nil {
+			fields = append(fields, info.Children)
+		}
+		for _, field := range fields {
+			fieldName := name + "." + field.Name
+			if field.Name == "" {
+				fieldName = name + ".children"
+			}
+			report.Fields = append(report.Fields, CoverageEntry{Name: fieldName, Count: c.fields[field]})
+			if len(field.Kinds) < 2 {
+				continue
+			}
+			for _, member := range field.Kinds {
+				report.Members = append(report.Members, CoverageEntry{
+					Name:  fieldName + ": " + member,
+					Count: c.members[field][member],
+				})
+			}
+		}
+	}
+	for _, info := range c.grammar.Named {
+		addKind(info)
+	}
+	for _, info := range c.grammar.Unnamed {
+		addKind(info)
+	}
+
+	for kind := range c.handled {
+		if c.reached[kind] == 0 {
+			report.Unreached = append(report.Unreached, kind)
+		}
+	}
+
+	compareEntries := func(a, b CoverageEntry) int {
+		return strings.Compare(a.Name, b.Name)
+	}
+	slices.SortFunc(report.Kinds, compareEntries)
+	slices.SortFunc(report.Fields, compareEntries)
+	slices.SortFunc(report.Members, compareEntries)
+	slices.Sort(report.Unreached)
+	return report
+}
+
+// Unused returns the names of the entries that never occurred.
+func Unused(entries []CoverageEntry) []string {
+	names := []string{}
+	for _, entry := range entries {
+		if entry.Count == 0 {
+			names = append(names, entry.Name)
+		}
+	}
+	return names
+}
+
+func (r CoverageReport) String() string {
+	builder := &strings.Builder{}
+	writeSection := func(title string, names []string) {
+		fmt.Fprintf(builder, "%s (%d):\n", title, len(names))
+		for _, name := range names {
+			fmt.Fprintf(builder, "  %s\n", name)
+		}
+	}
+
+	covered := func(entries []CoverageEntry) string {
+		return fmt.Sprintf("%d/%d", len(entries)-len(Unused(entries)), len(entries))
+	}
+	fmt.Fprintf(builder, "Kinds: %s\n", covered(r.Kinds))
+	fmt.Fprintf(builder, "Fields: %s\n", covered(r.Fields))
+	fmt.Fprintf(builder, "Members: %s\n", covered(r.Members))
+	writeSection("Kinds that never occur", Unused(r.Kinds))
+	writeSection("Fields that never occur", Unused(r.Fields))
+	writeSection("Members that never occur", Unused(r.Members))
+	if len(r.Unreached) > 0 {
+		writeSection("Visitor methods never reached", r.Unreached)
+	}
+	return builder.String()
+}
@@ -0,0 +1,68 @@
+package runtime
+
+import (
+	goruntime "runtime"
+	"sync"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+var cursorPool sync.Pool
+
+// AcquireCursor returns a cursor positioned at the given node, reusing a cursor from
+// a pool if one is available. Call ReleaseCursor when it's no longer needed.
+func AcquireCursor(node *tree_sitter.Node) *tree_sitter.TreeCursor {
+	if cursor, ok := cursorPool.Get().(*tree_sitter.TreeCursor); ok {
+		cursor.Reset(*node)
+		return cursor
+	}
+
+	// The pool can drop cursors at any time, so they're closed when collected
+	cursor := node.Walk()
+	goruntime.SetFinalizer(cursor, (*tree_sitter.TreeCursor).Close)
+	return cursor
+}
+
+// ReleaseCursor returns a cursor from AcquireCursor to the pool. The cursor must not
+// be used afterwards.
+func ReleaseCursor(cursor *tree_sitter.TreeCursor) {
+	cursorPool.Put(cursor)
+}
+
+// FieldID is the numeric ID of a field, resolved from its name the first time it's
+// used. Comparing IDs avoids looking the name up on every call.
+type FieldID struct {
+	Name string
+	once sync.Once
+	id   uint16
+}
+
+// NewFieldID creates a FieldID for the field with the given name.
+func NewFieldID(name string) *FieldID {
+	return &FieldID{Name: name}
+}
+
+// ID returns the field's ID in the language of the given node. Generated packages
+// only contain one language, so it's resolved once.
+func (f *FieldID) ID(node *tree_sitter.Node) uint16 {
+	f.once.Do(func() {
+		f.id = node.Language().FieldIdForName(f.Name)
+	})
+	return f.id
+}
+
+// ChildrenByFieldID returns the children of the node in the field with the given ID.
+// It's the same as `Node.ChildrenByFieldName`, without looking up the field's name.
+func ChildrenByFieldID(node *tree_sitter.Node, fieldID uint16, cursor *tree_sitter.TreeCursor) []tree_sitter.Node {
+	children := []tree_sitter.Node{}
+	if fieldID == 0 {
+		return children
+	}
+	cursor.Reset(*node)
+	for ok := cursor.GotoFirstChild(); ok; ok = cursor.GotoNextSibling() {
+		if cursor.FieldId() == fieldID {
+			children = append(children, *cursor.Node())
+		}
+	}
+	return children
+}
@@ -0,0 +1,463 @@
+package runtime
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// EditKind is the kind of change an Edit describes.
+type EditKind int
+
+const (
+	// EditInsert is a node that only exists in the new tree.
+	EditInsert EditKind = iota
+	// EditDelete is a node that only exists in the old tree.
+	EditDelete
+	// EditUpdate is a node whose own tokens changed, such as an operator or, for
+	// leaves, their text. Changes to its children are separate edits.
+	EditUpdate
+	// EditMove is an unchanged node that moved to a different position among its
+	// siblings.
+	EditMove
+)
+
+func (k EditKind) String() string {
+	switch k {
+	case EditInsert:
+		return "insert"
+	case EditDelete:
+		return "delete"
+	case EditUpdate:
+		return "update"
+	case EditMove:
+		return "move"
+	}
+	return "EditKind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// Edit is a change between two trees.
+type Edit struct {
+	Kind EditKind
+	// The path to the node in each tree, e.g. `module[1]/function_definition.name/identifier`.
+	// Empty if the node isn't in that tree.
+	OldPath Path
+	NewPath Path
+	// The nodes in each tree, or nil if the node isn't in that tree.
+	OldNode *tree_sitter.Node
+	NewNode *tree_sitter.Node
+	// The nodes wrapped in their generated types. Nil if the node isn't in that tree,
+	// or its kind has no generated type.
+	Old TypedNode
+	New TypedNode
+}
+
+// TreeDiff is the list of edits that turn one tree into another.
+type TreeDiff struct {
+	grammar   *Grammar
+	oldSource []byte
+	newSource []byte
+	Edits     []Edit
+}
+
+// DiffTrees compares the trees rooted at the given nodes. Children are aligned by the
+// field they're in, using the grammar, then by kind and tokens within each field, so
+// changes are reported against the node they belong to rather than by position.
+// Whitespace between tokens is ignored, so reformatting isn't a change.
+//
+// Moves are only detected between siblings in the same field. Each node is passed to
+// wrap to create the typed nodes in the edits. If wrap is nil, they're left empty.
+func DiffTrees(
+	grammar *Grammar,
+	oldRoot *tree_sitter.Node,
+	newRoot *tree_sitter.Node,
+	oldSource []byte,
+	newSource []byte,
+	wrap func(*tree_sitter.Node) (TypedNode, error),
+) *TreeDiff {
+	d := &differ{
+		grammar:   grammar,
+		oldSource: oldSource,
+		newSource: newSource,
+		wrap:      wrap,
+	}
+	oldChild := diffChild{node: *oldRoot, path: Path{Root: oldRoot.Kind()}}
+	newChild := diffChild{node: *newRoot, path: Path{Root: newRoot.Kind()}}
+	if oldRoot.Kind() == newRoot.Kind() {
+		d.diffNodes(&oldChild.node, &newChild.node, oldChild.path, newChild.path)
+	} else {
+		d.add(EditDelete, &oldChild, nil)
+		d.add(EditInsert, nil, &newChild)
+	}
+	return &TreeDiff{
+		grammar:   grammar,
+		oldSource: oldSource,
+		newSource: newSource,
+		Edits:     d.edits,
+	}
+}
+
+func (d *TreeDiff) String() string {
+	b := strings.Builder{}
+	for _, edit := range d.Edits {
+		var node *tree_sitter.Node
+		switch edit.Kind {
+		case EditInsert:
+			node = edit.NewNode
+			fmt.Fprintf(&b, "+ %s %s: %s\n", d.typeName(node), edit.NewPath, formatText(node, d.newSource))
+		case EditDelete:
+			node = edit.OldNode
+			fmt.Fprintf(&b, "- %s %s: %s\n", d.typeName(node), edit.OldPath, formatText(node, d.oldSource))
+		case EditUpdate:
+			node = edit.NewNode
+			fmt.Fprintf(
+				&b,
+				"~ %s %s: %s -> %s\n",
+				d.typeName(node),
+				edit.NewPath,
+				formatText(edit.OldNode, d.oldSource),
+				formatText(edit.NewNode, d.newSource),
+			)
+		case EditMove:
+			node = edit.NewNode
+			fmt.Fprintf(&b, "> %s %s -> %s: %s\n", d.typeName(node), edit.OldPath, edit.NewPath, formatText(node, d.newSource))
+		}
+	}
+	return b.String()
+}
+
+// typeName returns the name of the generated struct for the node, or its kind if it
+// has none.
+func (d *TreeDiff) typeName(node *tree_sitter.Node) string {
+	if info, ok := d.grammar.Lookup(node.Kind(), node.IsNamed()); ok {
+		return info.StructName
+	}
+	return node.Kind()
+}
+
+// formatText returns the node's text on one line, shortened if it's long.
+func formatText(node *tree_sitter.Node, source []byte) string {
+	text := strings.Join(strings.Fields(node.Utf8Text(source)), " ")
+	if len(text) > 40 {
+		text = text[:37] + "..."
+	}
+	return strconv.Quote(text)
+}
+
+type differ struct {
+	grammar   *Grammar
+	oldSource []byte
+	newSource []byte
+	wrap      func(*tree_sitter.Node) (TypedNode, error)
+	edits     []Edit
+}
+
+// diffChild is a child in one of a node's fields.
+type diffChild struct {
+	node tree_sitter.Node
+	path Path
+}
+
+// add records an edit, wrapping its nodes.
+func (d *differ) add(kind EditKind, oldChild *diffChild, newChild *diffChild) {
+	edit := Edit{Kind: kind}
+	if oldChild != nil {
+		edit.OldPath = oldChild.path
+		edit.OldNode = &oldChild.node
+		edit.Old = d.wrapNode(edit.OldNode)
+	}
+	if newChild != nil {
+		edit.NewPath = newChild.path
+		edit.NewNode = &newChild.node
+		edit.New = d.wrapNode(edit.NewNode)
+	}
+	d.edits = append(d.edits, edit)
+}
+
+// wrapNode returns the typed node for the node, or nil if it can't be wrapped.
+func (d *differ) wrapNode(node *tree_sitter.Node) TypedNode {
+	if d.wrap == nil {
+		return nil
+	}
+	typed, err := d.wrap(node)
+	if err != nil {
+		return nil
+	}
+	return typed
+}
+
+// diffNodes adds the edits between two nodes of the same kind at the given paths.
+func (d *differ) diffNodes(oldNode *tree_sitter.Node, newNode *tree_sitter.Node, oldPath Path, newPath Path) {
+	oldFields, fieldNames := d.children(oldNode, oldPath, nil)
+	newFields, fieldNames := d.children(newNode, newPath, fieldNames)
+
+	// Changes to unnamed tokens outside of fields, or to leaves, aren't in any field.
+	// They're recorded before the changes to the node's children.
+	if !d.sameTokens(oldNode, newNode) {
+		d.add(EditUpdate, &diffChild{node: *oldNode, path: oldPath}, &diffChild{node: *newNode, path: newPath})
+	}
+	for _, fieldName := range fieldNames {
+		d.diffChildren(oldFields[fieldName], newFields[fieldName])
+	}
+}
+
+// sameTokens reports whether two nodes of the same kind have the same text if they're
+// leaves, or otherwise the same unnamed tokens outside of fields. Whitespace between
+// tokens isn't part of any token, so it's ignored.
+func (d *differ) sameTokens(oldNode *tree_sitter.Node, newNode *tree_sitter.Node) bool {
+	if oldNode.ChildCount() == 0 || newNode.ChildCount() == 0 {
+		return oldNode.ChildCount() == newNode.ChildCount() && bytes.Equal(nodeBytes(oldNode, d.oldSource), nodeBytes(newNode, d.newSource))
+	}
+
+	oldCursor := AcquireCursor(oldNode)
+	defer ReleaseCursor(oldCursor)
+	newCursor := AcquireCursor(newNode)
+	defer ReleaseCursor(newCursor)
+	// nextToken moves the cursor to its next unnamed child outside of a field
+	nextToken := func(cursor *tree_sitter.TreeCursor, first bool) bool {
+		ok := false
+		if first {
+			ok = cursor.GotoFirstChild()
+		} else {
+			ok = cursor.GotoNextSibling()
+		}
+		for ; ok; ok = cursor.GotoNextSibling() {
+			if cursor.FieldId() == 0 && !cursor.Node().IsNamed() {
+				return true
+			}
+		}
+		return false
+	}
+
+	oldOk, newOk := nextToken(oldCursor, true), nextToken(newCursor, true)
+	for oldOk && newOk {
+		oldToken, newToken := oldCursor.Node(), newCursor.Node()
+		if oldToken.Kind() != newToken.Kind() || !bytes.Equal(nodeBytes(oldToken, d.oldSource), nodeBytes(newToken, d.newSource)) {
+			return false
+		}
+		oldOk, newOk = nextToken(oldCursor, false), nextToken(newCursor, false)
+	}
+	return oldOk == newOk
+}
+
+// nodeBytes returns the node's source, without copying it.
+func nodeBytes(node *tree_sitter.Node, source []byte) []byte {
+	return source[node.StartByte():node.EndByte()]
+}
+
+// children groups the node's children by field name, using "" for named children
+// outside of fields. Field names are appended to fieldNames in the order they occur
+// in the node, followed by the rest of those the grammar declares.
+func (d *differ) children(node *tree_sitter.Node, path Path, fieldNames []string) (map[string][]diffChild, []string) {
+	addFieldName := func(name string) {
+		for _, existing := range fieldNames {
+			if existing == name {
+				return
+			}
+		}
+		fieldNames = append(fieldNames, name)
+	}
+
+	fields := map[string][]diffChild{}
+	cursor := AcquireCursor(node)
+	defer ReleaseCursor(cursor)
+	for ok := cursor.GotoFirstChild(); ok; ok = cursor.GotoNextSibling() {
+		child := cursor.Node()
+		fieldName := cursor.FieldName()
+		if fieldName == "" && !child.IsNamed() {
+			continue
+		}
+		addFieldName(fieldName)
+		fields[fieldName] = append(fields[fieldName], diffChild{node: *child})
+	}
+
+	// Fields that are empty in this tree might not be in the other
+	info, ok := d.grammar.Lookup(node.Kind(), node.IsNamed())
+	if ok {
+		for _, field := range info.Fields {
+			addFieldName(field.Name)
+		}
+	}
+	addFieldName("")
+
+	// Paths count extras separately from the other children, as NodePath does
+	for fieldName, children := range fields {
+		count, extras := 0, 0
+		for i := range children {
+			if !children[i].node.IsExtra() {
+				count++
+			}
+		}
+		multiple := indexed(info, fieldName, count)
+		index := 0
+		for i := range children {
+			step := PathStep{Field: fieldName, Index: -1, Kind: children[i].node.Kind()}
+			if children[i].node.IsExtra() {
+				step.Extra = true
+				step.Index = extras
+				extras++
+			} else {
+				if multiple {
+					step.Index = index
+				}
+				index++
+			}
+			children[i].path = path.Child(step)
+		}
+	}
+	return fields, fieldNames
+}
+
+// hashChildren hashes each child's tokens, so that identical children can be found
+// without comparing every pair of them in full.
+func hashChildren(children []diffChild, source []byte) []uint64 {
+	hashes := make([]uint64, len(children))
+	for i := range children {
+		hashes[i] = HashNode(&children[i].node, source, EqualOptions{})
+	}
+	return hashes
+}
+
+// diffChildren adds the edits between the children of a field in each tree.
+//
+// Identical children in the same order are unchanged. Identical children out of
+// order are moves, and the remaining children are paired up by kind and compared
+// recursively. Anything left over is deleted or inserted.
+func (d *differ) diffChildren(oldChildren []diffChild, newChildren []diffChild) {
+	// Children are identical if they have the same tokens, however they're spaced. The
+	// hashes rule out most pairs before comparing them token by token.
+	oldHashes := hashChildren(oldChildren, d.oldSource)
+	newHashes := hashChildren(newChildren, d.newSource)
+	same := func(i int, j int) bool {
+		return oldHashes[i] == newHashes[j] &&
+			EqualNodes(&oldChildren[i].node, &newChildren[j].node, d.oldSource, d.newSource, EqualOptions{})
+	}
+
+	oldMatched, newMatched := commonSubsequence(len(oldChildren), len(newChildren), same)
+
+	pair := func(matches func(i int, j int) bool, pair func(oldChild *diffChild, newChild *diffChild)) {
+		for i := range oldChildren {
+			if oldMatched[i] {
+				continue
+			}
+			for j := range newChildren {
+				if !newMatched[j] && matches(i, j) {
+					oldMatched[i], newMatched[j] = true, true
+					pair(&oldChildren[i], &newChildren[j])
+					break
+				}
+			}
+		}
+	}
+	pair(
+		same,
+		func(oldChild *diffChild, newChild *diffChild) { d.add(EditMove, oldChild, newChild) },
+	)
+	pair(
+		func(i int, j int) bool {
+			return oldChildren[i].node.Kind() == newChildren[j].node.Kind()
+		},
+		func(oldChild *diffChild, newChild *diffChild) {
+			d.diffNodes(&oldChild.node, &newChild.node, oldChild.path, newChild.path)
+		},
+	)
+
+	for i := range oldChildren {
+		if !oldMatched[i] {
+			d.add(EditDelete, &oldChildren[i], nil)
+		}
+	}
+	for j := range newChildren {
+		if !newMatched[j] {
+			d.add(EditInsert, nil, &newChildren[j])
+		}
+	}
+}
+
+// commonSubsequence finds a longest common subsequence of two sequences with the given
+// lengths, where same reports whether their elements at i and j are the same. It
+// returns which elements of each sequence are in it.
+//
+// It uses Hirschberg's algorithm, which needs space linear in the lengths of the
+// sequences rather than a table of every pair of elements.
+func commonSubsequence(oldLen int, newLen int, same func(i int, j int) bool) ([]bool, []bool) {
+	oldMatched := make([]bool, oldLen)
+	newMatched := make([]bool, newLen)
+	var match func(oldStart, oldEnd, newStart, newEnd int)
+	match = func(oldStart, oldEnd, newStart, newEnd int) {
+		// Elements that are the same at either end are always in a longest subsequence.
+		// Edits usually leave most of a sequence alone, so this does most of the work.
+		for oldStart < oldEnd && newStart < newEnd && same(oldStart, newStart) {
+			oldMatched[oldStart], newMatched[newStart] = true, true
+			oldStart++
+			newStart++
+		}
+		for oldStart < oldEnd && newStart < newEnd && same(oldEnd-1, newEnd-1) {
+			oldMatched[oldEnd-1], newMatched[newEnd-1] = true, true
+			oldEnd--
+			newEnd--
+		}
+		if oldStart == oldEnd || newStart == newEnd {
+			return
+		}
+		if oldEnd-oldStart == 1 {
+			for j := newStart; j < newEnd; j++ {
+				if same(oldStart, j) {
+					oldMatched[oldStart], newMatched[j] = true, true
+					return
+				}
+			}
+			return
+		}
+
+		// Split the old sequence in half, and the new one where the lengths of the
+		// subsequences of each half add up to the most
+		middle := (oldStart + oldEnd) / 2
+		forward := subsequenceLengths(oldStart, middle, newEnd-newStart, func(i, k int) bool {
+			return same(i, newStart+k)
+		})
+		backward := subsequenceLengths(oldEnd-1, middle-1, newEnd-newStart, func(i, k int) bool {
+			return same(i, newEnd-1-k)
+		})
+		split, best := newStart, -1
+		for k := 0; k <= newEnd-newStart; k++ {
+			if length := forward[k] + backward[newEnd-newStart-k]; length > best {
+				split, best = newStart+k, length
+			}
+		}
+		match(oldStart, middle, newStart, split)
+		match(middle, oldEnd, split, newEnd)
+	}
+	match(0, oldLen, 0, newLen)
+	return oldMatched, newMatched
+}
+
+// subsequenceLengths returns the lengths of the longest common subsequences of the old
+// elements from start up to, but not including, end, and the first k new elements,
+// for each k up to newLen. If end is before start, the old elements are visited
+// backwards. same reports whether the old element at i and the kth new element are the
+// same.
+func subsequenceLengths(start int, end int, newLen int, same func(i int, k int) bool) []int {
+	step := 1
+	if end < start {
+		step = -1
+	}
+	lengths := make([]int, newLen+1)
+	for i := start; i != end; i += step {
+		// The length for the previous old elements and the previous k
+		diagonal := 0
+		for k := 1; k <= newLen; k++ {
+			above := lengths[k]
+			if same(i, k-1) {
+				lengths[k] = diagonal + 1
+			} else {
+				lengths[k] = max(lengths[k], lengths[k-1])
+			}
+			diagonal = above
+		}
+	}
+	return lengths
+}
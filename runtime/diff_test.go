@@ -0,0 +1,66 @@
+package runtime
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// lcsLength computes the length of a longest common subsequence with the full dynamic
+// programming table, to check commonSubsequence against.
+func lcsLength(a []byte, b []byte) int {
+	table := make([][]int, len(a)+1)
+	for i := range table {
+		table[i] = make([]int, len(b)+1)
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			if a[i-1] == b[j-1] {
+				table[i][j] = table[i-1][j-1] + 1
+			} else {
+				table[i][j] = max(table[i-1][j], table[i][j-1])
+			}
+		}
+	}
+	return table[len(a)][len(b)]
+}
+
+func TestCommonSubsequence(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	sequence := func() []byte {
+		// A small alphabet, so that there are many matches to choose between
+		s := make([]byte, random.Intn(40))
+		for i := range s {
+			s[i] = "abcd"[random.Intn(4)]
+		}
+		return s
+	}
+
+	for range 2000 {
+		a, b := sequence(), sequence()
+		aMatched, bMatched := commonSubsequence(len(a), len(b), func(i, j int) bool { return a[i] == b[j] })
+
+		aIndexes, bIndexes := []int{}, []int{}
+		for i, matched := range aMatched {
+			if matched {
+				aIndexes = append(aIndexes, i)
+			}
+		}
+		for j, matched := range bMatched {
+			if matched {
+				bIndexes = append(bIndexes, j)
+			}
+		}
+		if len(aIndexes) != len(bIndexes) {
+			t.Fatalf("Matched %d elements of %q but %d of %q", len(aIndexes), a, len(bIndexes), b)
+		}
+		// Matched elements pair up in order
+		for k := range aIndexes {
+			if a[aIndexes[k]] != b[bIndexes[k]] {
+				t.Fatalf("Matched %q at %d with %q at %d in %q and %q", a[aIndexes[k]], aIndexes[k], b[bIndexes[k]], bIndexes[k], a, b)
+			}
+		}
+		if expected := lcsLength(a, b); len(aIndexes) != expected {
+			t.Fatalf("Expected a common subsequence of length %d for %q and %q, got %d", expected, a, b, len(aIndexes))
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package runtime
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// ANSI escape codes used when dumping with colour.
+const (
+	colorReset  = "\x1b[0m"
+	colorBold   = "\x1b[1m"
+	colorDim    = "\x1b[2m"
+	colorRed    = "\x1b[31m"
+	colorGreen  = "\x1b[32m"
+	colorYellow = "\x1b[33m"
+	colorCyan   = "\x1b[36m"
+)
+
+// DumpOptions controls how DumpTree prints a tree. The zero value prints every node
+// with two spaces of indentation and no colour.
+type DumpOptions struct {
+	// The string each level of the tree is indented by. Defaults to two spaces.
+	Indent string
+	// The number of levels below the node to print, or zero to print the whole tree.
+	MaxDepth int
+	// Highlight the parts of each line with ANSI escape codes.
+	Color bool
+	// Skip unnamed tokens, such as punctuation and keywords.
+	HideUnnamed bool
+}
+
+// DumpTree prints the tree rooted at the given node, one node per line. Each line has
+// the field the node is in, the name of its generated struct, its range and, for
+// leaves, its text, e.g. `name: Identifier 3:5-3:9 "main"`.
+func DumpTree(w io.Writer, grammar *Grammar, node *tree_sitter.Node, source []byte, options DumpOptions) error {
+	if options.Indent == "" {
+		options.Indent = "  "
+	}
+	color := func(code string, text string) string {
+		if !options.Color {
+			return text
+		}
+		return code + text + colorReset
+	}
+
+	cursor := AcquireCursor(node)
+	defer ReleaseCursor(cursor)
+
+	var dump func(node *tree_sitter.Node, field string, depth int) error
+	dump = func(node *tree_sitter.Node, field string, depth int) error {
+		line := strings.Builder{}
+		line.WriteString(strings.Repeat(options.Indent, depth))
+		if field != "" {
+			line.WriteString(color(colorCyan, field+":") + " ")
+		}
+
+		name := node.Kind()
+		if info, ok := grammar.Lookup(node.Kind(), node.IsNamed()); ok {
+			name = info.StructName
+		} else if !node.IsNamed() {
+			name = strconv.Quote(name)
+		}
+		switch {
+		case node.IsError():
+			line.WriteString(color(colorRed+colorBold, "ERROR"))
+		case node.IsMissing():
+			line.WriteString(color(colorRed+colorBold, "MISSING "+name))
+		case node.IsNamed():
+			line.WriteString(color(colorBold, name))
+		default:
+			line.WriteString(name)
+		}
+
+		line.WriteString(" " + color(colorDim, formatPoint(node.StartPosition())+"-"+formatPoint(node.EndPosition())))
+
+		children := []tree_sitter.Node{}
+		fields := []string{}
+		cursor.Reset(*node)
+		for ok := cursor.GotoFirstChild(); ok; ok = cursor.GotoNextSibling() {
+			child := cursor.Node()
+			if options.HideUnnamed && !child.IsNamed() {
+				continue
+			}
+			children = append(children, *child)
+			fields = append(fields, cursor.FieldName())
+		}
+
+		if node.ChildCount() == 0 {
+			line.WriteString(" " + color(colorGreen, strconv.Quote(node.Utf8Text(source))))
+		} else if options.MaxDepth > 0 && depth >= options.MaxDepth && len(children) > 0 {
+			hidden := fmt.Sprintf("(%d children hidden)", len(children))
+			if len(children) == 1 {
+				hidden = "(1 child hidden)"
+			}
+			line.WriteString(" " + color(colorYellow, hidden))
+			children = nil
+		}
+
+		line.WriteString("\n")
+		if _, err := io.WriteString(w, line.String()); err != nil {
+			return err
+		}
+
+		for i := range children {
+			if err := dump(&children[i], fields[i], depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return dump(node, "", 0)
+}
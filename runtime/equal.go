@@ -0,0 +1,166 @@
+package runtime
+
+import (
+	"encoding/binary"
+	"hash"
+	"hash/fnv"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// EqualOptions controls which differences EqualNodes and HashNode ignore. The zero
+// value compares kinds, fields and the text of every token. Whitespace between tokens
+// is never compared, as it isn't part of the tree.
+type EqualOptions struct {
+	// Ignore extra nodes, such as comments.
+	IgnoreExtras bool
+	// Ignore whitespace inside tokens, such as in comments and strings.
+	IgnoreWhitespace bool
+	// Ignore the names of identifiers, as long as they're renamed consistently, so
+	// that `def f(a): return a` equals `def g(b): return b` (alpha-equivalence).
+	IgnoreIdentifiers bool
+	// The kinds that are identifiers. Defaults to the named kinds ending in
+	// `identifier`, such as `identifier` and `type_identifier`.
+	IdentifierKinds []SyntaxKind
+}
+
+// isIdentifier reports whether the node's name is ignored by the options.
+func (o *EqualOptions) isIdentifier(node *tree_sitter.Node) bool {
+	if !o.IgnoreIdentifiers || !node.IsNamed() {
+		return false
+	}
+	if o.IdentifierKinds == nil {
+		return strings.HasSuffix(node.Kind(), "identifier")
+	}
+	for _, kind := range o.IdentifierKinds {
+		if kind == node.Kind() {
+			return true
+		}
+	}
+	return false
+}
+
+// text returns the text of a token that's compared.
+func (o *EqualOptions) text(node *tree_sitter.Node, source []byte) string {
+	text := node.Utf8Text(source)
+	if o.IgnoreWhitespace {
+		text = strings.Join(strings.Fields(text), "")
+	}
+	return text
+}
+
+// equalChild is a child that's compared, and the field it's in.
+type equalChild struct {
+	field string
+	node  tree_sitter.Node
+}
+
+// children returns the children of the node that are compared.
+func (o *EqualOptions) children(node *tree_sitter.Node) []equalChild {
+	children := make([]equalChild, 0, node.ChildCount())
+	cursor := AcquireCursor(node)
+	defer ReleaseCursor(cursor)
+	for ok := cursor.GotoFirstChild(); ok; ok = cursor.GotoNextSibling() {
+		child := cursor.Node()
+		if o.IgnoreExtras && child.IsExtra() {
+			continue
+		}
+		children = append(children, equalChild{field: cursor.FieldName(), node: *child})
+	}
+	return children
+}
+
+// EqualNodes reports whether the subtrees rooted at the given nodes have the same
+// kinds, fields and token text. The nodes can come from different trees of the same
+// language.
+func EqualNodes(a *tree_sitter.Node, b *tree_sitter.Node, sourceA []byte, sourceB []byte, options EqualOptions) bool {
+	// Identifier names in each tree that have been matched with the other's
+	renamedA := map[string]string{}
+	renamedB := map[string]string{}
+
+	var equal func(a *tree_sitter.Node, b *tree_sitter.Node) bool
+	equal = func(a *tree_sitter.Node, b *tree_sitter.Node) bool {
+		if a.Kind() != b.Kind() || a.IsNamed() != b.IsNamed() {
+			return false
+		}
+
+		if options.isIdentifier(a) {
+			nameA, nameB := a.Utf8Text(sourceA), b.Utf8Text(sourceB)
+			renamedToB, okA := renamedA[nameA]
+			renamedToA, okB := renamedB[nameB]
+			if !okA && !okB {
+				renamedA[nameA], renamedB[nameB] = nameB, nameA
+				return true
+			}
+			return okA && okB && renamedToB == nameB && renamedToA == nameA
+		}
+
+		if a.ChildCount() == 0 || b.ChildCount() == 0 {
+			return a.ChildCount() == b.ChildCount() && options.text(a, sourceA) == options.text(b, sourceB)
+		}
+
+		childrenA, childrenB := options.children(a), options.children(b)
+		if len(childrenA) != len(childrenB) {
+			return false
+		}
+		for i := range childrenA {
+			if childrenA[i].field != childrenB[i].field || !equal(&childrenA[i].node, &childrenB[i].node) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return equal(a, b)
+}
+
+// HashNode returns a hash of the subtree rooted at the given node, such that nodes
+// that are equal according to EqualNodes with the same options have the same hash.
+// Hashes are stable across processes, so they can be stored.
+func HashNode(node *tree_sitter.Node, source []byte, options EqualOptions) uint64 {
+	h := fnv.New64a()
+	// Identifiers are numbered in the order they first occur
+	identifiers := map[string]int{}
+
+	var add func(node *tree_sitter.Node)
+	add = func(node *tree_sitter.Node) {
+		writeHashString(h, node.Kind())
+		if node.IsNamed() {
+			h.Write([]byte{1})
+		} else {
+			h.Write([]byte{0})
+		}
+
+		if options.isIdentifier(node) {
+			name := node.Utf8Text(source)
+			if _, ok := identifiers[name]; !ok {
+				identifiers[name] = len(identifiers)
+			}
+			h.Write(binary.LittleEndian.AppendUint64(nil, uint64(identifiers[name])))
+			return
+		}
+
+		if node.ChildCount() == 0 {
+			writeHashString(h, options.text(node, source))
+			return
+		}
+
+		children := options.children(node)
+		h.Write(binary.LittleEndian.AppendUint64(nil, uint64(len(children))))
+		for i := range children {
+			writeHashString(h, children[i].field)
+			add(&children[i].node)
+		}
+	}
+
+	add(node)
+	return h.Sum64()
+}
+
+// writeHashString adds a string to the hash, prefixed by its length so that adjacent
+// strings can't run into each other.
+func writeHashString(h hash.Hash64, s string) {
+	h.Write(binary.LittleEndian.AppendUint64(nil, uint64(len(s))))
+	h.Write([]byte(s))
+}
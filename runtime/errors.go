@@ -0,0 +1,89 @@
+// Package runtime contains the code shared by all packages generated by gent.
+package runtime
+
+import (
+	"fmt"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// SyntaxKind is the Tree-sitter kind of a node. Generated packages declare their own
+// `SyntaxKind` alias, which is interchangeable with this one.
+type SyntaxKind = string
+
+// KindMismatchError is returned when a node is converted to a typed node that can't
+// represent its kind.
+type KindMismatchError struct {
+	// The kinds the typed node can represent. Empty if the kind isn't known at all.
+	Expected []SyntaxKind
+	// The kind of the node that was converted.
+	Actual SyntaxKind
+	// The position of the node that was converted.
+	Range tree_sitter.Range
+}
+
+func NewKindMismatchError(expected []SyntaxKind, node *tree_sitter.Node) *KindMismatchError {
+	return &KindMismatchError{
+		Expected: expected,
+		Actual:   node.Kind(),
+		Range:    node.Range(),
+	}
+}
+
+func (e *KindMismatchError) Error() string {
+	if len(e.Expected) == 0 {
+		return fmt.Sprintf("Node at %s is of unknown kind %s", formatPoint(e.Range.StartPoint), e.Actual)
+	}
+	if len(e.Expected) == 1 {
+		return fmt.Sprintf("Node at %s is a %s, not a %s", formatPoint(e.Range.StartPoint), e.Actual, e.Expected[0])
+	}
+	return fmt.Sprintf("Node at %s is a %s, not in %v", formatPoint(e.Range.StartPoint), e.Actual, e.Expected)
+}
+
+// MissingFieldError is returned when a required field accessor is called on a node
+// that doesn't have a child in that field.
+type MissingFieldError struct {
+	// The kind of the node the field was accessed on.
+	Parent SyntaxKind
+	// The Tree-sitter name of the field.
+	Field string
+	// The position of the node the field was accessed on.
+	Range tree_sitter.Range
+}
+
+func NewMissingFieldError(parent *tree_sitter.Node, field string) *MissingFieldError {
+	return &MissingFieldError{
+		Parent: parent.Kind(),
+		Field:  field,
+		Range:  parent.Range(),
+	}
+}
+
+func (e *MissingFieldError) Error() string {
+	return fmt.Sprintf("Node of kind %s at %s has no child of name %s", e.Parent, formatPoint(e.Range.StartPoint), e.Field)
+}
+
+// MissingChildError is returned when `TypedChild` is called on a node that has no
+// named children.
+type MissingChildError struct {
+	// The kind of the node the child was accessed on.
+	Parent SyntaxKind
+	// The position of the node the child was accessed on.
+	Range tree_sitter.Range
+}
+
+func NewMissingChildError(parent *tree_sitter.Node) *MissingChildError {
+	return &MissingChildError{
+		Parent: parent.Kind(),
+		Range:  parent.Range(),
+	}
+}
+
+func (e *MissingChildError) Error() string {
+	return fmt.Sprintf("No children found on node of kind %s at %s", e.Parent, formatPoint(e.Range.StartPoint))
+}
+
+// formatPoint formats a point as a 1-indexed `line:column` string.
+func formatPoint(point tree_sitter.Point) string {
+	return fmt.Sprintf("%d:%d", point.Row+1, point.Column+1)
+}
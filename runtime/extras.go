@@ -0,0 +1,62 @@
+package runtime
+
+import (
+	"slices"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// Each extra belongs to a single node. Extras between two siblings are trailing extras
+// of the previous sibling if they start on the line it ends on, such as a comment at
+// the end of a statement, and leading extras of the next sibling otherwise. Extras
+// before the first sibling are leading extras of it, and extras after the last sibling
+// trailing extras of it.
+
+// LeadingExtrasOf returns the extra nodes preceding the node that belong to it, in
+// source order, wrapped in T.
+func LeadingExtrasOf[T Wrapper](node *tree_sitter.Node) []*T {
+	extras := []tree_sitter.Node{}
+	sibling := node.PrevSibling()
+	for ; sibling != nil && sibling.IsExtra(); sibling = sibling.PrevSibling() {
+		extras = append(extras, *sibling)
+	}
+	// The extras on the line the previous sibling ends on are its trailing extras, and
+	// they're the last ones found
+	if sibling != nil {
+		row := sibling.EndPosition().Row
+		for len(extras) > 0 && extras[len(extras)-1].StartPosition().Row == row {
+			extras = extras[:len(extras)-1]
+		}
+	}
+	slices.Reverse(extras)
+	return wrapExtras[T](extras)
+}
+
+// TrailingExtrasOf returns the extra nodes following the node that belong to it,
+// wrapped in T.
+func TrailingExtrasOf[T Wrapper](node *tree_sitter.Node) []*T {
+	extras := []tree_sitter.Node{}
+	sibling := node.NextSibling()
+	for ; sibling != nil && sibling.IsExtra(); sibling = sibling.NextSibling() {
+		extras = append(extras, *sibling)
+	}
+	// If there's a next sibling, only the extras on the line the node ends on are its
+	// trailing extras
+	if sibling != nil {
+		row := node.EndPosition().Row
+		end := 0
+		for end < len(extras) && extras[end].StartPosition().Row == row {
+			end++
+		}
+		extras = extras[:end]
+	}
+	return wrapExtras[T](extras)
+}
+
+func wrapExtras[T Wrapper](extras []tree_sitter.Node) []*T {
+	output := make([]*T, 0, len(extras))
+	for _, extra := range extras {
+		output = append(output, &T{Node: extra})
+	}
+	return output
+}
@@ -0,0 +1,82 @@
+package runtime
+
+import "slices"
+
+// Grammar describes the node types of a language, as declared in node-types.json.
+// Generated packages expose their grammar as a package-level `Grammar` variable.
+type Grammar struct {
+	// Named kinds. Named and unnamed kinds can share a name, so they're kept
+	// separately.
+	Named map[SyntaxKind]*KindInfo
+	// Unnamed kinds, such as operators and keywords.
+	Unnamed map[SyntaxKind]*KindInfo
+	// The concrete kinds each supertype can be.
+	Supertypes map[SyntaxKind][]SyntaxKind
+	// Kinds that can appear anywhere in the tree, such as comments.
+	Extras []SyntaxKind
+	// The kind of the root node of every tree, if known.
+	Root SyntaxKind
+}
+
+// KindInfo describes a single concrete node kind.
+type KindInfo struct {
+	Kind  SyntaxKind
+	Named bool
+	// The name of the generated struct for the kind.
+	StructName string
+	Fields     []FieldInfo
+	// The named children that aren't in a field, if the kind has any.
+	Children *FieldInfo
+}
+
+// FieldInfo describes a field, or the children of a node.
+type FieldInfo struct {
+	// The Tree-sitter name of the field. Empty for children.
+	Name string
+	// The name of the generated accessor method.
+	Accessor string
+	Multiple bool
+	Required bool
+	// The concrete kinds the field can hold.
+	Kinds []SyntaxKind
+}
+
+// Lookup returns the information about a named or unnamed kind.
+func (g *Grammar) Lookup(kind SyntaxKind, named bool) (*KindInfo, bool) {
+	kinds := g.Unnamed
+	if named {
+		kinds = g.Named
+	}
+	info, ok := kinds[kind]
+	return info, ok
+}
+
+// IsExtra reports whether the given kind is an extra.
+func (g *Grammar) IsExtra(kind SyntaxKind) bool {
+	return slices.Contains(g.Extras, kind)
+}
+
+// Field returns the information about the field with the given Tree-sitter name.
+func (k *KindInfo) Field(name string) (*FieldInfo, bool) {
+	for i := range k.Fields {
+		if k.Fields[i].Name == name {
+			return &k.Fields[i], true
+		}
+	}
+	return nil, false
+}
+
+// Allows reports whether the field can hold a node of the given kind.
+func (f *FieldInfo) Allows(kind SyntaxKind) bool {
+	return slices.Contains(f.Kinds, kind)
+}
+
+// hasNamedKind reports whether the field can hold any named nodes.
+func (g *Grammar) hasNamedKind(field FieldInfo) bool {
+	for _, kind := range field.Kinds {
+		if _, ok := g.Named[kind]; ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package runtime
+
+import (
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// JSONNode is the JSON representation of a typed node, produced by the generated
+// `ToJSONNode` and `MarshalJSON` methods.
+type JSONNode struct {
+	Kind  SyntaxKind `json:"kind"`
+	Range JSONRange  `json:"range"`
+	// The source text of the node. Only set on leaf nodes, and only when the source
+	// is available.
+	Text *string `json:"text,omitempty"`
+	// Values are either a `*JSONNode` or, for fields that can hold multiple nodes,
+	// a `[]*JSONNode`.
+	Fields map[string]any `json:"fields,omitempty"`
+	// Named children that aren't in a field.
+	Children []*JSONNode `json:"children,omitempty"`
+}
+
+type JSONRange struct {
+	StartByte  uint      `json:"start_byte"`
+	EndByte    uint      `json:"end_byte"`
+	StartPoint JSONPoint `json:"start_point"`
+	EndPoint   JSONPoint `json:"end_point"`
+}
+
+type JSONPoint struct {
+	Row    uint `json:"row"`
+	Column uint `json:"column"`
+}
+
+// NewJSONNode creates a JSONNode for the given node without any fields or children.
+// If source is non-nil and the node is a leaf, the node's text is included.
+func NewJSONNode(node *tree_sitter.Node, source []byte) *JSONNode {
+	output := &JSONNode{
+		Kind: node.Kind(),
+		Range: JSONRange{
+			StartByte:  node.StartByte(),
+			EndByte:    node.EndByte(),
+			StartPoint: JSONPoint{Row: node.StartPosition().Row, Column: node.StartPosition().Column},
+			EndPoint:   JSONPoint{Row: node.EndPosition().Row, Column: node.EndPosition().Column},
+		},
+		Fields: map[string]any{},
+	}
+	if source != nil && node.ChildCount() == 0 {
+		text := string(source[node.StartByte():node.EndByte()])
+		output.Text = &text
+	}
+	return output
+}
+
+// ToJSONNode converts the node and its fields and children into their JSON
+// representation, using the grammar to find them rather than typed accessors. The
+// output is the same as the generated `ToJSONNode` methods, which delegate to it in
+// compact mode.
+func ToJSONNode(grammar *Grammar, node *tree_sitter.Node, source []byte) *JSONNode {
+	output := NewJSONNode(node, source)
+	info, ok := grammar.Lookup(node.Kind(), node.IsNamed())
+	if !ok {
+		return output
+	}
+
+	cursor := node.Walk()
+	defer cursor.Close()
+	for _, field := range info.Fields {
+		if field.Multiple {
+			children := []*JSONNode{}
+			for _, child := range node.ChildrenByFieldName(field.Name, cursor) {
+				children = append(children, ToJSONNode(grammar, &child, source))
+			}
+			output.Fields[field.Name] = children
+			continue
+		}
+		if child := node.ChildByFieldName(field.Name); child != nil {
+			output.Fields[field.Name] = ToJSONNode(grammar, child, source)
+		}
+	}
+
+	if info.Children == nil {
+		return output
+	}
+	cursor.Reset(*node)
+	for ok := cursor.GotoFirstChild(); ok; ok = cursor.GotoNextSibling() {
+		if cursor.FieldId() != 0 {
+			continue
+		}
+		child := cursor.Node()
+		if !child.IsNamed() || child.IsExtra() {
+			continue
+		}
+		output.Children = append(output.Children, ToJSONNode(grammar, child, source))
+		if !info.Children.Multiple {
+			break
+		}
+	}
+	return output
+}
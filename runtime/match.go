@@ -0,0 +1,173 @@
+package runtime
+
+import (
+	"fmt"
+	"maps"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// Match is the result of matching a node, containing the nodes captured with Capture.
+type Match struct {
+	// The node that was matched.
+	Node tree_sitter.Node
+	// The source code of the tree, used by Text.
+	Source   []byte
+	Captures map[string]tree_sitter.Node
+}
+
+// Matcher reports whether a node matches a pattern, adding any captured nodes to the
+// match. Generated packages contain a `Match<Struct>` matcher for each kind, and a
+// `<Struct>With<Field>` matcher for each field of each kind, which can be combined with
+// the matchers below. There's also a short `<Field>` matcher for each field name, e.g.
+// `MatchCall(Function(MatchAttribute(...)))`, which matches any kind with the field. It
+// gets trailing underscores if a kind has the same name, such as `Attribute_`.
+type Matcher func(match *Match, node *tree_sitter.Node) bool
+
+// Match matches the node against the pattern, returning the match if it succeeds.
+// source is only needed if the pattern uses Text.
+func (m Matcher) Match(node *tree_sitter.Node, source []byte) (*Match, bool) {
+	match := &Match{Node: *node, Source: source, Captures: map[string]tree_sitter.Node{}}
+	if !m(match, node) {
+		return nil, false
+	}
+	return match, true
+}
+
+// FindAll returns a match for every node in the tree rooted at the given node that
+// matches the pattern, in depth-first order.
+func (m Matcher) FindAll(root *tree_sitter.Node, source []byte) []*Match {
+	matches := []*Match{}
+	cursor := root.Walk()
+	defer cursor.Close()
+
+	for {
+		if match, ok := m.Match(cursor.Node(), source); ok {
+			matches = append(matches, match)
+		}
+		if cursor.GotoFirstChild() {
+			continue
+		}
+		for !cursor.GotoNextSibling() {
+			if !cursor.GotoParent() {
+				return matches
+			}
+		}
+	}
+}
+
+// Any matches any node.
+func Any() Matcher {
+	return func(match *Match, node *tree_sitter.Node) bool {
+		return true
+	}
+}
+
+// And matches nodes that match all of the given matchers.
+func And(matchers ...Matcher) Matcher {
+	return func(match *Match, node *tree_sitter.Node) bool {
+		for _, matcher := range matchers {
+			if !matcher(match, node) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Or matches nodes that match any of the given matchers. Only the captures of the
+// first matcher that matches are kept.
+func Or(matchers ...Matcher) Matcher {
+	return func(match *Match, node *tree_sitter.Node) bool {
+		for _, matcher := range matchers {
+			attempt := *match
+			attempt.Captures = maps.Clone(match.Captures)
+			if matcher(&attempt, node) {
+				*match = attempt
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not matches nodes that don't match the given matcher. It never captures anything.
+func Not(matcher Matcher) Matcher {
+	return func(match *Match, node *tree_sitter.Node) bool {
+		attempt := *match
+		attempt.Captures = maps.Clone(match.Captures)
+		return !matcher(&attempt, node)
+	}
+}
+
+// Kind matches nodes of any of the given kinds, and any additional matchers.
+func Kind(kinds []SyntaxKind, matchers ...Matcher) Matcher {
+	set := NewKindSet(kinds...)
+	and := And(matchers...)
+	return func(match *Match, node *tree_sitter.Node) bool {
+		return node.IsNamed() && set.Contains(node.Kind()) && and(match, node)
+	}
+}
+
+// Text matches nodes whose source text is exactly the given text.
+func Text(text string) Matcher {
+	return func(match *Match, node *tree_sitter.Node) bool {
+		return match.Source != nil && node.Utf8Text(match.Source) == text
+	}
+}
+
+// Capture matches the same nodes as the given matcher, capturing the node under the
+// given name.
+func Capture(name string, matcher Matcher) Matcher {
+	return func(match *Match, node *tree_sitter.Node) bool {
+		if !matcher(match, node) {
+			return false
+		}
+		match.Captures[name] = *node
+		return true
+	}
+}
+
+// InField matches nodes with a child in the field with the given name that matches
+// the given matcher. If the field holds multiple nodes, any of them can match.
+func InField(name string, matcher Matcher) Matcher {
+	return func(match *Match, node *tree_sitter.Node) bool {
+		cursor := AcquireCursor(node)
+		defer ReleaseCursor(cursor)
+		for _, child := range node.ChildrenByFieldName(name, cursor) {
+			if matcher(match, &child) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// HasChild matches nodes with a named child that isn't in a field or an extra, and
+// that matches the given matcher.
+func HasChild(matcher Matcher) Matcher {
+	return func(match *Match, node *tree_sitter.Node) bool {
+		cursor := AcquireCursor(node)
+		defer ReleaseCursor(cursor)
+		for ok := cursor.GotoFirstChild(); ok; ok = cursor.GotoNextSibling() {
+			if cursor.FieldId() != 0 {
+				continue
+			}
+			child := cursor.Node()
+			if child.IsNamed() && !child.IsExtra() && matcher(match, child) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Captured returns the node captured under the given name as a T, using its
+// registered constructor.
+func Captured[T any, PT TypedNodePointer[T]](match *Match, name string) (*T, error) {
+	node, ok := match.Captures[name]
+	if !ok {
+		return nil, fmt.Errorf("No node captured as %s", name)
+	}
+	return Construct[T, PT](&node)
+}
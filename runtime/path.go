@@ -0,0 +1,303 @@
+package runtime
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// Path addresses a node by the fields and children leading to it from the root of its
+// tree, so it stays the same when the source is reformatted. Extras such as comments
+// are counted separately from the other children, so adding or removing them doesn't
+// change the paths of their siblings. Its textual form is the root's kind followed by
+// a step for each node, e.g. `module[1]/function_definition.body/block[0]/return_statement`:
+//
+//   - `.body` is the node in the `body` field, and `.body[2]` the third node in it.
+//   - `[0]` is the first named child outside of any field that isn't an extra.
+//   - `{0}` is the first extra child.
+//   - `/block` is the kind of the node. It's optional when parsing, but checked when
+//     given. Kinds that aren't identifiers, such as `+`, are quoted.
+//
+// Paths are encoded in JSON as their textual form.
+type Path struct {
+	Root  SyntaxKind
+	Steps []PathStep
+}
+
+// PathStep is a step from a node to one of its children.
+type PathStep struct {
+	// The field the child is in, or empty if it isn't in a field.
+	Field string
+	// The position of the child in the field, among the named children outside of
+	// fields that aren't extras, or among the extras if Extra is set. -1 if the field
+	// holds a single node.
+	Index int
+	// Whether the child is an extra, such as a comment. Extras are never in a field.
+	Extra bool
+	// The kind of the child. Empty if it isn't checked.
+	Kind SyntaxKind
+}
+
+// Child returns the path of a child of the node at this path.
+func (p Path) Child(step PathStep) Path {
+	return Path{Root: p.Root, Steps: append(p.Steps[:len(p.Steps):len(p.Steps)], step)}
+}
+
+func (p Path) String() string {
+	b := strings.Builder{}
+	b.WriteString(formatPathKind(p.Root))
+	for _, step := range p.Steps {
+		b.WriteString(step.String())
+	}
+	return b.String()
+}
+
+func (s PathStep) String() string {
+	text := ""
+	if s.Field != "" {
+		text = "." + s.Field
+	}
+	if s.Extra {
+		text += "{" + strconv.Itoa(s.Index) + "}"
+	} else if s.Index >= 0 {
+		text += "[" + strconv.Itoa(s.Index) + "]"
+	}
+	if s.Kind != "" {
+		text += "/" + formatPathKind(s.Kind)
+	}
+	return text
+}
+
+// MarshalText encodes the path in its textual form.
+func (p Path) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText decodes a path from its textual form.
+func (p *Path) UnmarshalText(text []byte) error {
+	path, err := ParsePath(string(text))
+	if err != nil {
+		return err
+	}
+	*p = path
+	return nil
+}
+
+// formatPathKind returns the kind as written in a path, quoting it if it isn't an
+// identifier.
+func formatPathKind(kind SyntaxKind) string {
+	if isPathIdentifier(kind) {
+		return kind
+	}
+	return strconv.Quote(kind)
+}
+
+func isPathIdentifier(s string) bool {
+	for i := range len(s) {
+		if !isPathIdentifierChar(s[i], i == 0) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func isPathIdentifierChar(c byte, first bool) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (!first && c >= '0' && c <= '9')
+}
+
+// ParsePath parses the textual form of a path, as described on Path.
+func ParsePath(text string) (Path, error) {
+	rest := text
+	fail := func(format string, args ...any) (Path, error) {
+		return Path{}, fmt.Errorf("Invalid path %q at offset %d: %s", text, len(text)-len(rest), fmt.Sprintf(format, args...))
+	}
+
+	identifier := func() string {
+		end := 0
+		for end < len(rest) && isPathIdentifierChar(rest[end], end == 0) {
+			end++
+		}
+		ident := rest[:end]
+		rest = rest[end:]
+		return ident
+	}
+	kind := func() (SyntaxKind, error) {
+		if !strings.HasPrefix(rest, `"`) {
+			return identifier(), nil
+		}
+		quoted, err := strconv.QuotedPrefix(rest)
+		if err != nil {
+			return "", err
+		}
+		rest = rest[len(quoted):]
+		return strconv.Unquote(quoted)
+	}
+
+	root, err := kind()
+	if err != nil || root == "" {
+		return fail("expected the kind of the root")
+	}
+	path := Path{Root: root}
+
+	for rest != "" {
+		step := PathStep{Index: -1}
+		if strings.HasPrefix(rest, ".") {
+			rest = rest[1:]
+			step.Field = identifier()
+			if step.Field == "" {
+				return fail("expected a field name")
+			}
+		} else if !strings.HasPrefix(rest, "[") && !strings.HasPrefix(rest, "{") {
+			return fail("expected a field or index")
+		}
+
+		if strings.HasPrefix(rest, "[") || strings.HasPrefix(rest, "{") {
+			closing := "]"
+			if rest[0] == '{' {
+				if step.Field != "" {
+					return fail("extras aren't in fields")
+				}
+				closing = "}"
+				step.Extra = true
+			}
+			end := strings.Index(rest, closing)
+			if end == -1 {
+				return fail("expected %s", closing)
+			}
+			index, err := strconv.Atoi(rest[1:end])
+			if err != nil || index < 0 {
+				return fail("expected an index")
+			}
+			step.Index = index
+			rest = rest[end+1:]
+		}
+
+		if strings.HasPrefix(rest, "/") {
+			rest = rest[1:]
+			step.Kind, err = kind()
+			if err != nil || step.Kind == "" {
+				return fail("expected a kind")
+			}
+		}
+		path.Steps = append(path.Steps, step)
+	}
+
+	return path, nil
+}
+
+// indexed reports whether the children in a field are addressed by index. This is the
+// case for fields that can hold multiple nodes, and for children outside of fields.
+func indexed(info *KindInfo, field string, count int) bool {
+	if field == "" || count > 1 {
+		return true
+	}
+	if info != nil {
+		if fieldInfo, ok := info.Field(field); ok {
+			return fieldInfo.Multiple
+		}
+	}
+	return false
+}
+
+// NodePath returns the path of the node from the root of its tree. Unnamed tokens
+// outside of fields, such as punctuation, can't be addressed.
+//
+// Extras are indexed among the extras of their parent, and other children among the
+// other children in the same field, so that adding or removing extras doesn't change
+// the paths of other nodes.
+func NodePath(grammar *Grammar, node *tree_sitter.Node) (Path, error) {
+	steps := []PathStep{}
+	for child, parent := node, node.Parent(); parent != nil; child, parent = parent, parent.Parent() {
+		info, _ := grammar.Lookup(parent.Kind(), parent.IsNamed())
+
+		step := PathStep{Index: -1, Kind: child.Kind()}
+		found := false
+		counts := map[string]int{}
+		extras := 0
+		cursor := AcquireCursor(parent)
+		for ok := cursor.GotoFirstChild(); ok; ok = cursor.GotoNextSibling() {
+			field := cursor.FieldName()
+			sibling := cursor.Node()
+			if field == "" && !sibling.IsNamed() {
+				if sibling.Id() == child.Id() {
+					ReleaseCursor(cursor)
+					return Path{}, fmt.Errorf("Node of kind %s isn't in a field, so it can't be addressed", child.Kind())
+				}
+				continue
+			}
+			if sibling.IsExtra() {
+				if !found && sibling.Id() == child.Id() {
+					found = true
+					step.Extra = true
+					step.Index = extras
+				}
+				extras++
+				continue
+			}
+			if !found && sibling.Id() == child.Id() {
+				found = true
+				step.Field = field
+				step.Index = counts[field]
+			}
+			counts[field]++
+		}
+		ReleaseCursor(cursor)
+
+		if !found {
+			return Path{}, fmt.Errorf("Node of kind %s isn't a child of its parent", child.Kind())
+		}
+		if !step.Extra && !indexed(info, step.Field, counts[step.Field]) {
+			step.Index = -1
+		}
+		steps = append(steps, step)
+		node = parent
+	}
+
+	for i, j := 0, len(steps)-1; i < j; i, j = i+1, j-1 {
+		steps[i], steps[j] = steps[j], steps[i]
+	}
+	return Path{Root: node.Kind(), Steps: steps}, nil
+}
+
+// ResolvePath returns the node at the path from the given root.
+func ResolvePath(root *tree_sitter.Node, path Path) (*tree_sitter.Node, error) {
+	if root.Kind() != path.Root {
+		return nil, fmt.Errorf("Expected root of kind %s, got %s", path.Root, root.Kind())
+	}
+
+	node := root
+	resolved := Path{Root: path.Root}
+	for _, step := range path.Steps {
+		resolved = resolved.Child(step)
+		index := max(step.Index, 0)
+
+		var next *tree_sitter.Node
+		cursor := AcquireCursor(node)
+		for ok := cursor.GotoFirstChild(); ok; ok = cursor.GotoNextSibling() {
+			if cursor.FieldName() != step.Field {
+				continue
+			}
+			child := cursor.Node()
+			if (step.Field == "" && !child.IsNamed()) || child.IsExtra() != step.Extra {
+				continue
+			}
+			if index == 0 {
+				next = child
+				break
+			}
+			index--
+		}
+		ReleaseCursor(cursor)
+
+		if next == nil {
+			return nil, fmt.Errorf("No node at %s", resolved)
+		}
+		if step.Kind != "" && next.Kind() != step.Kind {
+			return nil, fmt.Errorf("Expected node of kind %s at %s, got %s", step.Kind, resolved, next.Kind())
+		}
+		node = next
+	}
+	return node, nil
+}
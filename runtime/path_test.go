@@ -0,0 +1,121 @@
+package runtime
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParsePath(t *testing.T) {
+	for _, text := range []string{
+		"module",
+		"module[1]/function_definition.body/block[0]/return_statement",
+		"module{0}/comment",
+		"binary_operator.operator/\"+\"",
+		"call.arguments[0]",
+	} {
+		path, err := ParsePath(text)
+		if err != nil {
+			t.Fatalf("Failed to parse %q: %v", text, err)
+		}
+		if path.String() != text {
+			t.Errorf("Expected %q to round-trip, got %q", text, path.String())
+		}
+	}
+
+	path, err := ParsePath(`module[1].body/block{0}/comment.value/"+"`)
+	if err != nil {
+		t.Fatalf("Failed to parse path: %v", err)
+	}
+	expected := []PathStep{
+		{Index: 1, Kind: ""},
+		{Field: "body", Index: -1, Kind: "block"},
+		{Index: 0, Extra: true, Kind: "comment"},
+		{Field: "value", Index: -1, Kind: "+"},
+	}
+	if len(path.Steps) != len(expected) {
+		t.Fatalf("Expected %d steps, got %v", len(expected), path.Steps)
+	}
+	for i, step := range expected {
+		if path.Steps[i] != step {
+			t.Errorf("Expected step %d to be %+v, got %+v", i, step, path.Steps[i])
+		}
+	}
+
+	for text, message := range map[string]string{
+		"":                 "offset 0: expected the kind of the root",
+		"module.":          "offset 7: expected a field name",
+		"module/block":     "offset 6: expected a field or index",
+		"module[x]":        "offset 6: expected an index",
+		"module[-1]":       "offset 6: expected an index",
+		"module[0":         "offset 6: expected ]",
+		"module{0":         "offset 6: expected }",
+		"module.body{0}":   "offset 11: extras aren't in fields",
+		"module[0]/":       "offset 10: expected a kind",
+		`module[0]/"block`: "offset 10: expected a kind",
+	} {
+		_, err := ParsePath(text)
+		if err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("Expected an error parsing %q containing %q, got %v", text, message, err)
+		}
+	}
+}
+
+func TestNodePathAndResolvePath(t *testing.T) {
+	source := "# Run\nx = 1\ndef f():\n    return x\n"
+	root := parsePython(t, source)
+	grammar := &Grammar{}
+
+	returnStatement := findKind(t, root, "return_statement")
+	path, err := NodePath(grammar, returnStatement)
+	if err != nil {
+		t.Fatalf("Failed to get path: %v", err)
+	}
+	// The comment is counted among the extras, so the function is the second child
+	if text := path.String(); text != "module[1]/function_definition.body/block[0]/return_statement" {
+		t.Fatalf("Unexpected path %s", text)
+	}
+	node, err := ResolvePath(root, path)
+	if err != nil {
+		t.Fatalf("Failed to resolve path: %v", err)
+	}
+	if node.Id() != returnStatement.Id() {
+		t.Fatalf("Expected the path to resolve to the return statement, got %s", node.Kind())
+	}
+
+	comment := findKind(t, root, "comment")
+	path, err = NodePath(grammar, comment)
+	if err != nil {
+		t.Fatalf("Failed to get path: %v", err)
+	}
+	if text := path.String(); text != "module{0}/comment" {
+		t.Fatalf("Unexpected path %s", text)
+	}
+	node, err = ResolvePath(root, path)
+	if err != nil {
+		t.Fatalf("Failed to resolve path: %v", err)
+	}
+	if text := node.Utf8Text([]byte(source)); text != "# Run" {
+		t.Fatalf("Expected the comment, got %q", text)
+	}
+
+	// Punctuation outside of fields can't be addressed
+	if _, err := NodePath(grammar, findKind(t, root, ":")); err == nil {
+		t.Fatalf("Expected an error for punctuation")
+	}
+
+	for text, message := range map[string]string{
+		"block":                         "Expected root of kind block",
+		"module[2]":                     "No node at module[2]",
+		"module{1}":                     "No node at module{1}",
+		"module[0]/function_definition": "Expected node of kind function_definition at module[0]/function_definition, got expression_statement",
+	} {
+		path, err := ParsePath(text)
+		if err != nil {
+			t.Fatalf("Failed to parse %q: %v", text, err)
+		}
+		_, err = ResolvePath(root, path)
+		if err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("Expected an error resolving %q containing %q, got %v", text, message, err)
+		}
+	}
+}
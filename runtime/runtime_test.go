@@ -0,0 +1,44 @@
+package runtime
+
+import (
+	"testing"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+	tree_sitter_python "github.com/tree-sitter/tree-sitter-python/bindings/go"
+)
+
+// parsePython parses Python source. Generated packages import the runtime, so these
+// tests use the grammar's bindings directly.
+func parsePython(t *testing.T, source string) *tree_sitter.Node {
+	t.Helper()
+	parser := tree_sitter.NewParser()
+	defer parser.Close()
+	if err := parser.SetLanguage(tree_sitter.NewLanguage(tree_sitter_python.Language())); err != nil {
+		t.Fatalf("Failed to set language: %v", err)
+	}
+	tree := parser.Parse([]byte(source), nil)
+	t.Cleanup(tree.Close)
+	return tree.RootNode()
+}
+
+// findKind returns the first node of the given kind in the tree, in source order.
+func findKind(t *testing.T, root *tree_sitter.Node, kind SyntaxKind) *tree_sitter.Node {
+	t.Helper()
+	if node := findKindOrNil(root, kind); node != nil {
+		return node
+	}
+	t.Fatalf("Failed to find a node of kind %s", kind)
+	return nil
+}
+
+func findKindOrNil(node *tree_sitter.Node, kind SyntaxKind) *tree_sitter.Node {
+	if node.Kind() == kind {
+		return node
+	}
+	for i := range node.ChildCount() {
+		if found := findKindOrNil(node.Child(i), kind); found != nil {
+			return found
+		}
+	}
+	return nil
+}
@@ -0,0 +1,259 @@
+package runtime
+
+import (
+	"slices"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// ScopeRoles tags kinds with the roles BuildScopes uses to build a scope tree, similar
+// to the captures in Tree-sitter's `locals.scm` queries. Generated packages declare
+// theirs from the `roles` in the gent config.
+type ScopeRoles struct {
+	// Kinds that introduce a scope, e.g. `function_definition`.
+	Scopes []SyntaxKind
+	// Kinds that declare names, mapped to the field holding the name, e.g.
+	// `function_definition: name`. An empty field declares the names in the node's
+	// children outside of fields, e.g. for `parameters`.
+	Definitions map[SyntaxKind]string
+	// Kinds that are names, e.g. `identifier`. They're references unless they're
+	// declared by a definition or ignored.
+	References []SyntaxKind
+	// Kinds that are searched for names when they're in a definition's field, e.g.
+	// `pattern_list` for `a, b = ...`. Other kinds in the field don't declare anything.
+	Patterns []SyntaxKind
+	// Kinds mapped to a field whose names, including those nested in it, are neither
+	// definitions nor references, e.g. `attribute: attribute` for the `b` in `a.b`.
+	Ignored map[SyntaxKind]string
+}
+
+// Scopes is the scope tree of a tree, with every declaration and reference in it.
+type Scopes struct {
+	Root         *Scope
+	Declarations []*Declaration
+	References   []*Reference
+}
+
+// Scope is a node that introduces a scope.
+type Scope struct {
+	Node tree_sitter.Node
+	// The typed node, e.g. a `*FunctionDefinition`. Nil if it can't be wrapped.
+	Typed        TypedNode
+	Parent       *Scope
+	Children     []*Scope
+	Declarations []*Declaration
+}
+
+// Declaration is a name declared in a scope.
+type Declaration struct {
+	Name string
+	// The node holding the name.
+	Node tree_sitter.Node
+	// The node declaring the name, e.g. a `function_definition`.
+	Definition tree_sitter.Node
+	// The typed definition. Nil if it can't be wrapped.
+	Typed      TypedNode
+	Scope      *Scope
+	References []*Reference
+}
+
+// Reference is a use of a name.
+type Reference struct {
+	Name string
+	Node tree_sitter.Node
+	// The typed node, e.g. an `*Identifier`. Nil if it can't be wrapped.
+	Typed TypedNode
+	Scope *Scope
+	// The declaration the name refers to, or nil if it isn't declared in any
+	// enclosing scope, e.g. for builtins.
+	Declaration *Declaration
+}
+
+// BuildScopes builds the scope tree of the tree rooted at the given node, which is
+// always a scope. Each name is declared in the innermost scope enclosing its
+// definition, or the scope around it if the definition is a scope itself, as with a
+// function's name.
+//
+// References resolve to the nearest enclosing scope that declares their name, to the
+// last declaration before them, or the first one if they're all after them. Each node
+// is passed to wrap to create the typed nodes.
+func BuildScopes(
+	roles *ScopeRoles,
+	root *tree_sitter.Node,
+	source []byte,
+	wrap func(*tree_sitter.Node) (TypedNode, error),
+) *Scopes {
+	typed := func(node *tree_sitter.Node) TypedNode {
+		if wrap == nil {
+			return nil
+		}
+		typedNode, err := wrap(node)
+		if err != nil {
+			return nil
+		}
+		return typedNode
+	}
+	isScope := func(node *tree_sitter.Node) bool {
+		return node.IsNamed() && slices.Contains(roles.Scopes, node.Kind())
+	}
+	isReference := func(node *tree_sitter.Node) bool {
+		return node.IsNamed() && slices.Contains(roles.References, node.Kind())
+	}
+
+	scopes := &Scopes{Root: &Scope{Node: *root, Typed: typed(root)}}
+	// Names that have been declared, so aren't references
+	declared := map[uintptr]bool{}
+	// Nodes whose subtrees are ignored
+	ignored := map[uintptr]bool{}
+
+	cursor := AcquireCursor(root)
+	defer ReleaseCursor(cursor)
+	// fieldChildren returns the children in the field, or the named children outside
+	// of fields if the field is empty.
+	fieldChildren := func(node *tree_sitter.Node, field string) []tree_sitter.Node {
+		if field != "" {
+			return node.ChildrenByFieldName(field, cursor)
+		}
+		children := []tree_sitter.Node{}
+		cursor.Reset(*node)
+		for ok := cursor.GotoFirstChild(); ok; ok = cursor.GotoNextSibling() {
+			if cursor.FieldName() == "" && cursor.Node().IsNamed() {
+				children = append(children, *cursor.Node())
+			}
+		}
+		return children
+	}
+
+	var declare func(scope *Scope, definition *tree_sitter.Node, node *tree_sitter.Node)
+	declare = func(scope *Scope, definition *tree_sitter.Node, node *tree_sitter.Node) {
+		if isReference(node) {
+			declaration := &Declaration{
+				Name:       node.Utf8Text(source),
+				Node:       *node,
+				Definition: *definition,
+				Typed:      typed(definition),
+				Scope:      scope,
+			}
+			scope.Declarations = append(scope.Declarations, declaration)
+			scopes.Declarations = append(scopes.Declarations, declaration)
+			declared[node.Id()] = true
+			return
+		}
+		if !node.IsNamed() || !slices.Contains(roles.Patterns, node.Kind()) {
+			return
+		}
+		for _, child := range fieldChildren(node, "") {
+			declare(scope, definition, &child)
+		}
+	}
+
+	var visit func(scope *Scope, node *tree_sitter.Node)
+	visit = func(scope *Scope, node *tree_sitter.Node) {
+		if node.IsNamed() && !ignored[node.Id()] {
+			if field, ok := roles.Definitions[node.Kind()]; ok {
+				// Scopes declare their names in the scope around them
+				declarationScope := scope
+				if scope.Parent != nil && scope.Node.Id() == node.Id() {
+					declarationScope = scope.Parent
+				}
+				for _, child := range fieldChildren(node, field) {
+					declare(declarationScope, node, &child)
+				}
+			}
+			if field, ok := roles.Ignored[node.Kind()]; ok {
+				for _, child := range fieldChildren(node, field) {
+					ignored[child.Id()] = true
+				}
+			}
+			if isReference(node) && !declared[node.Id()] {
+				scopes.References = append(scopes.References, &Reference{
+					Name:  node.Utf8Text(source),
+					Node:  *node,
+					Typed: typed(node),
+					Scope: scope,
+				})
+			}
+		}
+
+		children := []tree_sitter.Node{}
+		cursor.Reset(*node)
+		for ok := cursor.GotoFirstChild(); ok; ok = cursor.GotoNextSibling() {
+			children = append(children, *cursor.Node())
+		}
+		for i := range children {
+			child := &children[i]
+			if ignored[node.Id()] {
+				ignored[child.Id()] = true
+			}
+			childScope := scope
+			if isScope(child) {
+				childScope = &Scope{Node: *child, Typed: typed(child), Parent: scope}
+				scope.Children = append(scope.Children, childScope)
+			}
+			visit(childScope, child)
+		}
+	}
+	visit(scopes.Root, root)
+
+	for _, reference := range scopes.References {
+		reference.Declaration = reference.Scope.resolve(reference.Name, &reference.Node)
+		if reference.Declaration != nil {
+			reference.Declaration.References = append(reference.Declaration.References, reference)
+		}
+	}
+	return scopes
+}
+
+// resolve returns the declaration a name used at the given node refers to.
+func (s *Scope) resolve(name string, node *tree_sitter.Node) *Declaration {
+	for scope := s; scope != nil; scope = scope.Parent {
+		var found *Declaration
+		for _, declaration := range scope.Declarations {
+			if declaration.Name != name {
+				continue
+			}
+			if found == nil || declaration.Node.StartByte() <= node.StartByte() {
+				found = declaration
+			}
+			if declaration.Node.StartByte() > node.StartByte() {
+				break
+			}
+		}
+		if found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// Lookup returns the last declaration of the name in the scope or the scopes
+// enclosing it, or nil if it isn't declared.
+func (s *Scope) Lookup(name string) *Declaration {
+	for scope := s; scope != nil; scope = scope.Parent {
+		for i := len(scope.Declarations) - 1; i >= 0; i-- {
+			if scope.Declarations[i].Name == name {
+				return scope.Declarations[i]
+			}
+		}
+	}
+	return nil
+}
+
+// ScopeOf returns the innermost scope whose node contains the node. A scope's own
+// node is in that scope.
+func (s *Scopes) ScopeOf(node *tree_sitter.Node) *Scope {
+	scope := s.Root
+	for {
+		var inner *Scope
+		for _, child := range scope.Children {
+			if child.Node.StartByte() <= node.StartByte() && node.EndByte() <= child.Node.EndByte() {
+				inner = child
+				break
+			}
+		}
+		if inner == nil {
+			return scope
+		}
+		scope = inner
+	}
+}
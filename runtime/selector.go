@@ -0,0 +1,371 @@
+package runtime
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// Selector is a compiled CSS-like selector over named nodes, such as
+// `class_definition > block function_definition[name="__init__"]`. Selectors are made
+// of compound selectors separated by combinators:
+//
+//   - `function_definition` matches nodes of that kind, or of any kind in that
+//     supertype. `*` matches any named node, other than ERROR and MISSING
+//     nodes.
+//   - `[name]` matches nodes with a child in the `name` field, `[name="main"]` nodes
+//     whose `name` child has the text `main`, and `[name!="main"]` the opposite.
+//   - `a b` matches `b` nodes inside an `a` node, and `a > b` those directly inside it.
+//   - `a, b` matches nodes that match either selector.
+type Selector struct {
+	text       string
+	alternates [][]compoundSelector
+}
+
+// compoundSelector matches a single node.
+type compoundSelector struct {
+	// Whether the node must be a direct child of the node matched by the previous
+	// compound selector, rather than any descendant.
+	child bool
+	// Nil if any named kind matches, other than ERROR and MISSING nodes.
+	kinds      *KindSet
+	attributes []attributeSelector
+}
+
+// attributeSelector matches a node by the child in one of its fields.
+type attributeSelector struct {
+	field string
+	// Empty if the field only has to be present.
+	op    string
+	value string
+}
+
+// SelectorError is returned when a selector can't be compiled.
+type SelectorError struct {
+	Selector string
+	// The byte offset in the selector of the problem.
+	Offset  int
+	Message string
+	// Similar kinds or field names from the grammar, if a kind or field is unknown.
+	Suggestions []string
+}
+
+func (e *SelectorError) Error() string {
+	message := fmt.Sprintf("Invalid selector %q at offset %d: %s", e.Selector, e.Offset, e.Message)
+	if len(e.Suggestions) > 0 {
+		quoted := []string{}
+		for _, suggestion := range e.Suggestions {
+			quoted = append(quoted, strconv.Quote(suggestion))
+		}
+		message += fmt.Sprintf(", did you mean %s?", strings.Join(quoted, " or "))
+	}
+	return message
+}
+
+// CompileSelector parses a selector, checking its kinds and fields against the grammar.
+// Fields have to be declared on at least one of the kinds they're used with.
+func CompileSelector(grammar *Grammar, text string) (*Selector, error) {
+	p := selectorParser{grammar: grammar, text: text}
+	selector := &Selector{text: text}
+
+	for {
+		compounds, err := p.parseComplex()
+		if err != nil {
+			return nil, err
+		}
+		selector.alternates = append(selector.alternates, compounds)
+
+		p.skipSpaces()
+		if p.done() {
+			return selector, nil
+		}
+		if !p.consume(",") {
+			return nil, p.fail("expected , or the end of the selector")
+		}
+	}
+}
+
+func (s *Selector) String() string {
+	return s.text
+}
+
+// Matches reports whether the node matches the selector. Its ancestors are checked
+// against the rest of the selector.
+func (s *Selector) Matches(node *tree_sitter.Node, source []byte) bool {
+	for _, compounds := range s.alternates {
+		if matchComplex(compounds, node, source) {
+			return true
+		}
+	}
+	return false
+}
+
+// Select returns the nodes in the tree rooted at the given node that match the
+// selector, in depth-first order.
+func (s *Selector) Select(root *tree_sitter.Node, source []byte) []tree_sitter.Node {
+	nodes := []tree_sitter.Node{}
+	cursor := root.Walk()
+	defer cursor.Close()
+
+	for {
+		node := cursor.Node()
+		if node.IsNamed() && s.Matches(node, source) {
+			nodes = append(nodes, *node)
+		}
+		if cursor.GotoFirstChild() {
+			continue
+		}
+		for !cursor.GotoNextSibling() {
+			if !cursor.GotoParent() {
+				return nodes
+			}
+		}
+	}
+}
+
+// matchComplex reports whether the node matches the last compound selector, and its
+// ancestors match the ones before it.
+func matchComplex(compounds []compoundSelector, node *tree_sitter.Node, source []byte) bool {
+	last := compounds[len(compounds)-1]
+	if !last.matches(node, source) {
+		return false
+	}
+	if len(compounds) == 1 {
+		return true
+	}
+
+	for ancestor := node.Parent(); ancestor != nil; ancestor = ancestor.Parent() {
+		if matchComplex(compounds[:len(compounds)-1], ancestor, source) {
+			return true
+		}
+		if last.child {
+			return false
+		}
+	}
+	return false
+}
+
+func (c *compoundSelector) matches(node *tree_sitter.Node, source []byte) bool {
+	if !node.IsNamed() {
+		return false
+	}
+	if c.kinds == nil && (node.IsError() || node.IsMissing()) {
+		return false
+	}
+	if c.kinds != nil && !c.kinds.Contains(node.Kind()) {
+		return false
+	}
+	for _, attribute := range c.attributes {
+		child := node.ChildByFieldName(attribute.field)
+		switch {
+		case child == nil:
+			return false
+		case attribute.op == "=" && child.Utf8Text(source) != attribute.value:
+			return false
+		case attribute.op == "!=" && child.Utf8Text(source) == attribute.value:
+			return false
+		}
+	}
+	return true
+}
+
+type selectorParser struct {
+	grammar *Grammar
+	text    string
+	offset  int
+}
+
+func (p *selectorParser) fail(format string, args ...any) *SelectorError {
+	return &SelectorError{Selector: p.text, Offset: p.offset, Message: fmt.Sprintf(format, args...)}
+}
+
+func (p *selectorParser) done() bool {
+	return p.offset >= len(p.text)
+}
+
+func (p *selectorParser) skipSpaces() bool {
+	start := p.offset
+	for !p.done() && strings.ContainsRune(" \t\n", rune(p.text[p.offset])) {
+		p.offset++
+	}
+	return p.offset > start
+}
+
+func (p *selectorParser) consume(prefix string) bool {
+	if strings.HasPrefix(p.text[p.offset:], prefix) {
+		p.offset += len(prefix)
+		return true
+	}
+	return false
+}
+
+func (p *selectorParser) identifier() string {
+	start := p.offset
+	for !p.done() && isPathIdentifierChar(p.text[p.offset], p.offset == start) {
+		p.offset++
+	}
+	return p.text[start:p.offset]
+}
+
+// parseComplex parses compound selectors separated by combinators, up to a comma or
+// the end of the selector.
+func (p *selectorParser) parseComplex() ([]compoundSelector, error) {
+	compounds := []compoundSelector{}
+	child := false
+	p.skipSpaces()
+	for {
+		compound, err := p.parseCompound()
+		if err != nil {
+			return nil, err
+		}
+		compound.child = child
+		compounds = append(compounds, compound)
+
+		spaced := p.skipSpaces()
+		child = p.consume(">")
+		p.skipSpaces()
+		if p.done() || strings.HasPrefix(p.text[p.offset:], ",") {
+			if child {
+				return nil, p.fail("expected a selector after >")
+			}
+			return compounds, nil
+		}
+		if !spaced && !child {
+			return nil, p.fail("expected a combinator")
+		}
+	}
+}
+
+// parseCompound parses a kind followed by attribute selectors.
+func (p *selectorParser) parseCompound() (compoundSelector, error) {
+	compound := compoundSelector{}
+	start := p.offset
+	// Fields can be used if they're declared on any of these kinds
+	candidates := slices.Collect(maps.Keys(p.grammar.Named))
+
+	kind := ""
+	if !p.consume("*") {
+		kind = p.identifier()
+		if kind != "" {
+			kinds, ok := p.grammar.Supertypes[kind]
+			if !ok {
+				if _, named := p.grammar.Named[kind]; !named {
+					p.offset = start
+					err := p.fail("unknown kind %q", kind)
+					err.Suggestions = suggest(kind, slices.Concat(candidates, slices.Collect(maps.Keys(p.grammar.Supertypes))))
+					return compound, err
+				}
+				kinds = []SyntaxKind{kind}
+			}
+			compound.kinds = NewKindSet(kinds...)
+			candidates = kinds
+		} else if !strings.HasPrefix(p.text[p.offset:], "[") {
+			return compound, p.fail("expected a kind, * or [")
+		}
+	}
+
+	for p.consume("[") {
+		attribute := attributeSelector{}
+		p.skipSpaces()
+		fieldStart := p.offset
+		attribute.field = p.identifier()
+		if attribute.field == "" {
+			return compound, p.fail("expected a field name")
+		}
+
+		fields := []string{}
+		for _, kind := range candidates {
+			info, ok := p.grammar.Named[kind]
+			if !ok {
+				continue
+			}
+			for _, field := range info.Fields {
+				if !slices.Contains(fields, field.Name) {
+					fields = append(fields, field.Name)
+				}
+			}
+		}
+		if !slices.Contains(fields, attribute.field) {
+			p.offset = fieldStart
+			err := p.fail("unknown field %q", attribute.field)
+			if kind != "" {
+				err.Message = fmt.Sprintf("unknown field %q on %s", attribute.field, kind)
+			}
+			err.Suggestions = suggest(attribute.field, fields)
+			return compound, err
+		}
+
+		p.skipSpaces()
+		for _, op := range []string{"=", "!="} {
+			if p.consume(op) {
+				attribute.op = op
+			}
+		}
+		if attribute.op != "" {
+			p.skipSpaces()
+			quoted, err := strconv.QuotedPrefix(p.text[p.offset:])
+			if err != nil {
+				return compound, p.fail("expected a quoted string")
+			}
+			p.offset += len(quoted)
+			attribute.value, _ = strconv.Unquote(quoted)
+			p.skipSpaces()
+		}
+		if !p.consume("]") {
+			return compound, p.fail("expected ]")
+		}
+		compound.attributes = append(compound.attributes, attribute)
+	}
+
+	return compound, nil
+}
+
+// suggest returns up to three of the candidates that are closest to the given name.
+func suggest(name string, candidates []string) []string {
+	type suggestion struct {
+		name     string
+		distance int
+	}
+	suggestions := []suggestion{}
+	for _, candidate := range candidates {
+		distance := editDistance(name, candidate)
+		if distance <= max(2, len(name)/3) || strings.Contains(candidate, name) {
+			suggestions = append(suggestions, suggestion{candidate, distance})
+		}
+	}
+	slices.SortFunc(suggestions, func(a, b suggestion) int {
+		return cmp.Or(cmp.Compare(a.distance, b.distance), cmp.Compare(a.name, b.name))
+	})
+
+	names := []string{}
+	for _, suggestion := range suggestions[:min(3, len(suggestions))] {
+		names = append(names, suggestion.name)
+	}
+	return names
+}
+
+// editDistance returns the Levenshtein distance between two strings.
+func editDistance(a string, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
@@ -0,0 +1,88 @@
+package runtime
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+// selectorGrammar describes the few Python kinds the selector tests use.
+var selectorGrammar = &Grammar{
+	Named: map[SyntaxKind]*KindInfo{
+		"call": {Kind: "call", Named: true, Fields: []FieldInfo{
+			{Name: "function", Kinds: []SyntaxKind{"identifier", "attribute"}},
+			{Name: "arguments", Kinds: []SyntaxKind{"argument_list"}},
+		}},
+		"function_definition": {Kind: "function_definition", Named: true, Fields: []FieldInfo{
+			{Name: "name", Kinds: []SyntaxKind{"identifier"}},
+			{Name: "body", Kinds: []SyntaxKind{"block"}},
+		}},
+		"identifier":    {Kind: "identifier", Named: true},
+		"attribute":     {Kind: "attribute", Named: true},
+		"argument_list": {Kind: "argument_list", Named: true},
+		"block":         {Kind: "block", Named: true},
+	},
+	Supertypes: map[SyntaxKind][]SyntaxKind{
+		"primary_expression": {"call", "identifier", "attribute"},
+	},
+}
+
+func TestCompileSelectorErrors(t *testing.T) {
+	for _, test := range []struct {
+		selector    string
+		offset      int
+		message     string
+		suggestions []string
+	}{
+		{"functoin_definition", 0, `unknown kind "functoin_definition"`, []string{"function_definition"}},
+		{"block > primary_expresion", 8, `unknown kind "primary_expresion"`, []string{"primary_expression"}},
+		{"call[fucntion]", 5, `unknown field "fucntion" on call`, []string{"function"}},
+		// Fields of every kind are allowed with `*`, and names containing the unknown
+		// one are suggested too
+		{"*[nam]", 2, `unknown field "nam"`, []string{"name"}},
+		{"call[function]", -1, "", nil},
+		{"call[arguments = 1]", 17, "expected a quoted string", nil},
+		{"call[arguments", 14, "expected ]", nil},
+		{"call, ", 6, "expected a kind, * or [", nil},
+		{"zzz", 0, `unknown kind "zzz"`, nil},
+	} {
+		_, err := CompileSelector(selectorGrammar, test.selector)
+		if test.offset == -1 {
+			if err != nil {
+				t.Errorf("Failed to compile %q: %v", test.selector, err)
+			}
+			continue
+		}
+		var selectorErr *SelectorError
+		if !errors.As(err, &selectorErr) {
+			t.Errorf("Expected a SelectorError compiling %q, got %v", test.selector, err)
+			continue
+		}
+		if selectorErr.Offset != test.offset || selectorErr.Message != test.message {
+			t.Errorf("Expected %q at offset %d compiling %q, got %q at offset %d", test.message, test.offset, test.selector, selectorErr.Message, selectorErr.Offset)
+		}
+		if !slices.Equal(selectorErr.Suggestions, test.suggestions) {
+			t.Errorf("Expected suggestions %v compiling %q, got %v", test.suggestions, test.selector, selectorErr.Suggestions)
+		}
+	}
+
+	_, err := CompileSelector(selectorGrammar, "call[fucntion]")
+	expected := `Invalid selector "call[fucntion]" at offset 5: unknown field "fucntion" on call, did you mean "function"?`
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error %q, got %v", expected, err)
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	candidates := []string{"identifier", "identifiers", "idents", "integer", "string", "dotted_identifier"}
+	if suggestions := suggest("identifer", candidates); !slices.Equal(suggestions, []string{"identifier", "identifiers"}) {
+		t.Errorf("Unexpected suggestions %v", suggestions)
+	}
+	// Closest first, and at most three, including names containing the given one
+	if suggestions := suggest("ident", candidates); !slices.Equal(suggestions, []string{"idents", "identifier", "identifiers"}) {
+		t.Errorf("Unexpected suggestions %v", suggestions)
+	}
+	if suggestions := suggest("expression", candidates); len(suggestions) != 0 {
+		t.Errorf("Expected no suggestions, got %v", suggestions)
+	}
+}
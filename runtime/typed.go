@@ -0,0 +1,133 @@
+package runtime
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// TypedNode is implemented by every generated node type. Hand-written types can
+// implement it too, e.g. to wrap nodes from a fork of a grammar.
+type TypedNode interface {
+	// AsNode returns the underlying Tree-sitter node.
+	AsNode() *tree_sitter.Node
+}
+
+// TypedNodePointer constrains the generic helpers below to types whose pointers are
+// typed nodes, so that they can be called as `Field[Identifier]` and return an
+// `*Identifier`.
+type TypedNodePointer[T any] interface {
+	*T
+	TypedNode
+}
+
+// Constructor creates a typed node from a node, returning an error if the node's kind
+// can't be represented by the type.
+type Constructor func(node *tree_sitter.Node) (TypedNode, error)
+
+var (
+	constructorsMu sync.RWMutex
+	constructors   = map[reflect.Type]Constructor{}
+)
+
+// Register adds the constructor of T to the registry used by Construct and the helpers
+// below. Generated packages register all of their types when they're initialised, so
+// this is only needed for hand-written types.
+func Register[T any, PT TypedNodePointer[T]](constructor func(node *tree_sitter.Node) (*T, error)) {
+	constructorsMu.Lock()
+	defer constructorsMu.Unlock()
+	constructors[reflect.TypeFor[T]()] = func(node *tree_sitter.Node) (TypedNode, error) {
+		typed, err := constructor(node)
+		if err != nil {
+			return nil, err
+		}
+		return PT(typed), nil
+	}
+}
+
+// LookupConstructor returns the registered constructor of the given type, which is the
+// type the constructor creates a pointer to.
+func LookupConstructor(typ reflect.Type) (Constructor, bool) {
+	constructorsMu.RLock()
+	defer constructorsMu.RUnlock()
+	constructor, ok := constructors[typ]
+	return constructor, ok
+}
+
+// Construct creates a T from the given node using its registered constructor.
+func Construct[T any, PT TypedNodePointer[T]](node *tree_sitter.Node) (*T, error) {
+	constructor, ok := LookupConstructor(reflect.TypeFor[T]())
+	if !ok {
+		return nil, fmt.Errorf("No constructor registered for %v", reflect.TypeFor[T]())
+	}
+	typed, err := constructor(node)
+	if err != nil {
+		return nil, err
+	}
+	return (*T)(typed.(PT)), nil
+}
+
+// Field returns the node in the given field as a T, returning an error if the field is
+// empty or its node can't be represented by T.
+func Field[T any, PT TypedNodePointer[T]](node *tree_sitter.Node, field *FieldID) (*T, error) {
+	id := field.ID(node)
+	if id == 0 {
+		return nil, NewMissingFieldError(node, field.Name)
+	}
+	child := node.ChildByFieldId(id)
+	if child == nil {
+		return nil, NewMissingFieldError(node, field.Name)
+	}
+	return Construct[T, PT](child)
+}
+
+// Fields returns the nodes in the given field as Ts. Nodes that can't be represented by
+// T, such as ERROR nodes, are skipped.
+func Fields[T any, PT TypedNodePointer[T]](node *tree_sitter.Node, field *FieldID, cursor *tree_sitter.TreeCursor) []*T {
+	output := []*T{}
+	for _, child := range ChildrenByFieldID(node, field.ID(node), cursor) {
+		if typed, err := Construct[T, PT](&child); err == nil {
+			output = append(output, typed)
+		}
+	}
+	return output
+}
+
+// Children returns the named children of the node that aren't extras as Ts, including
+// the ones in a field. Nodes that can't be represented by T, such as ERROR nodes, are
+// skipped.
+func Children[T any, PT TypedNodePointer[T]](node *tree_sitter.Node, cursor *tree_sitter.TreeCursor) []T {
+	output := []T{}
+	cursor.Reset(*node)
+	for ok := cursor.GotoFirstChild(); ok; ok = cursor.GotoNextSibling() {
+		child := cursor.Node()
+		if !child.IsNamed() || child.IsExtra() {
+			continue
+		}
+		if typed, err := Construct[T, PT](child); err == nil {
+			output = append(output, *typed)
+		}
+	}
+	return output
+}
+
+// Child returns the first named child of the node that isn't an extra as a T, including
+// the ones in a field, returning an error if there isn't one or it can't be represented
+// by T.
+func Child[T any, PT TypedNodePointer[T]](node *tree_sitter.Node, cursor *tree_sitter.TreeCursor) (T, error) {
+	cursor.Reset(*node)
+	for ok := cursor.GotoFirstChild(); ok; ok = cursor.GotoNextSibling() {
+		child := cursor.Node()
+		if !child.IsNamed() || child.IsExtra() {
+			continue
+		}
+		typed, err := Construct[T, PT](child)
+		if err != nil {
+			return *new(T), err
+		}
+		return *typed, nil
+	}
+	return *new(T), NewMissingChildError(node)
+}
@@ -0,0 +1,208 @@
+package runtime
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// FormatRule emits the text of a node to the printer, returning false to keep the
+// node's original text instead, in which case it mustn't emit anything. Generated
+// packages build one from a visitor with VisitorRule.
+//
+// Rules emit the node's children with Print. To keep the node's own text, they use
+// PrintOriginal, as Print would call the rule again.
+type FormatRule func(p *Printer, node *tree_sitter.Node) bool
+
+// VisitorRule creates a rule from a visitor, where visit calls the visitor's method
+// for the node's kind and returns false if the method emitted the node itself. If the
+// visitor embeds a Formatter, it's given the printer before each call. A nil visitor
+// gives a nil rule.
+func VisitorRule(visitor any, visit func(node *tree_sitter.Node) bool) FormatRule {
+	if visitor == nil {
+		return nil
+	}
+	setter, _ := visitor.(printerSetter)
+	return func(p *Printer, node *tree_sitter.Node) bool {
+		if setter != nil {
+			setter.setPrinter(p)
+		}
+		return !visit(node)
+	}
+}
+
+// Formatter gives the visitor methods of formatting rules access to the printer. Rules
+// embed it and emit text with the promoted Printer methods.
+type Formatter struct {
+	*Printer
+}
+
+func (f *Formatter) setPrinter(p *Printer) {
+	f.Printer = p
+}
+
+// printerSetter is implemented by visitors that embed a Formatter.
+type printerSetter interface {
+	setPrinter(p *Printer)
+}
+
+// Unparser re-emits the source of a tree after changes. Nodes that aren't replaced,
+// deleted or formatted by a rule keep their original text, including the whitespace
+// and comments between their children.
+type Unparser struct {
+	source []byte
+	rule   FormatRule
+	// Changes keyed by the ID of the node they apply to
+	replacements map[uintptr]func(p *Printer)
+	before       map[uintptr][]func(p *Printer)
+	after        map[uintptr][]func(p *Printer)
+}
+
+// NewUnparser creates an unparser for a tree parsed from the given source. rule is
+// called for every node that isn't replaced, and can be nil.
+func NewUnparser(source []byte, rule FormatRule) *Unparser {
+	return &Unparser{
+		source:       source,
+		rule:         rule,
+		replacements: map[uintptr]func(p *Printer){},
+		before:       map[uintptr][]func(p *Printer){},
+		after:        map[uintptr][]func(p *Printer){},
+	}
+}
+
+// Replace replaces the node with the text emitted by the given function, which can
+// print other nodes to synthesize new syntax from existing nodes.
+func (u *Unparser) Replace(node *tree_sitter.Node, emit func(p *Printer)) {
+	u.replacements[node.Id()] = emit
+}
+
+// ReplaceText replaces the node with the given text.
+func (u *Unparser) ReplaceText(node *tree_sitter.Node, text string) {
+	u.Replace(node, func(p *Printer) { p.Write(text) })
+}
+
+// Delete removes the node, along with the whitespace that separates it from its
+// previous sibling, or its next one if it's the first child.
+func (u *Unparser) Delete(node *tree_sitter.Node) {
+	u.replacements[node.Id()] = nil
+}
+
+// InsertBefore emits text before the node, which is kept.
+func (u *Unparser) InsertBefore(node *tree_sitter.Node, emit func(p *Printer)) {
+	u.before[node.Id()] = append(u.before[node.Id()], emit)
+}
+
+// InsertAfter emits text after the node, which is kept.
+func (u *Unparser) InsertAfter(node *tree_sitter.Node, emit func(p *Printer)) {
+	u.after[node.Id()] = append(u.after[node.Id()], emit)
+}
+
+// Unparse writes the source of the tree rooted at the given node.
+func (u *Unparser) Unparse(w io.Writer, root *tree_sitter.Node) error {
+	_, err := w.Write(u.Bytes(root))
+	return err
+}
+
+// Bytes returns the source of the tree rooted at the given node.
+func (u *Unparser) Bytes(root *tree_sitter.Node) []byte {
+	p := &Printer{unparser: u}
+	p.Print(root)
+	return p.output.Bytes()
+}
+
+// Printer collects the text emitted by an Unparser.
+type Printer struct {
+	unparser *Unparser
+	output   bytes.Buffer
+}
+
+// Write emits text.
+func (p *Printer) Write(text string) {
+	p.output.WriteString(text)
+}
+
+// Source returns the original source of the tree.
+func (p *Printer) Source() []byte {
+	return p.unparser.source
+}
+
+// Text returns the original text of the node.
+func (p *Printer) Text(node *tree_sitter.Node) string {
+	return node.Utf8Text(p.unparser.source)
+}
+
+// LineIndent returns the whitespace at the start of the line the node starts on in the
+// original source, to indent synthesized lines consistently.
+func (p *Printer) LineIndent(node *tree_sitter.Node) string {
+	source := p.unparser.source
+	start := int(node.StartByte())
+	lineStart := bytes.LastIndexByte(source[:start], '\n') + 1
+	line := string(source[lineStart:start])
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+}
+
+// Print emits a node, applying any changes to it and its descendants. Use it in
+// replacements and rules to emit existing nodes.
+func (p *Printer) Print(node *tree_sitter.Node) {
+	u := p.unparser
+	for _, emit := range u.before[node.Id()] {
+		emit(p)
+	}
+	if emit, ok := u.replacements[node.Id()]; ok {
+		if emit != nil {
+			emit(p)
+		}
+	} else if u.rule == nil || !u.rule(p, node) {
+		p.PrintOriginal(node)
+	}
+	for _, emit := range u.after[node.Id()] {
+		emit(p)
+	}
+}
+
+// PrintOriginal emits a node with its original text, but still applies any changes to
+// its descendants. Rules can use it to wrap a node's text without changing it.
+func (p *Printer) PrintOriginal(node *tree_sitter.Node) {
+	u := p.unparser
+	if node.ChildCount() == 0 {
+		p.output.Write(u.source[node.StartByte():node.EndByte()])
+		return
+	}
+
+	children := []tree_sitter.Node{}
+	cursor := AcquireCursor(node)
+	for ok := cursor.GotoFirstChild(); ok; ok = cursor.GotoNextSibling() {
+		children = append(children, *cursor.Node())
+	}
+	ReleaseCursor(cursor)
+
+	end := node.StartByte()
+	emitted := false
+	skipGap := false
+	for i := range children {
+		child := &children[i]
+		gap := u.source[end:child.StartByte()]
+		blank := len(bytes.TrimSpace(gap)) == 0
+		end = child.EndByte()
+
+		emit, replaced := u.replacements[child.Id()]
+		inserted := len(u.before[child.Id()]) > 0 || len(u.after[child.Id()]) > 0
+		if replaced && emit == nil && !inserted && blank {
+			// Deleted nodes take the whitespace before them with them, unless they're
+			// first, in which case they take the whitespace after them
+			skipGap = skipGap || !emitted
+			continue
+		}
+		if skipGap && blank {
+			gap = nil
+		}
+		skipGap = false
+		emitted = true
+
+		p.output.Write(gap)
+		p.Print(child)
+	}
+	p.output.Write(u.source[end:node.EndByte()])
+}
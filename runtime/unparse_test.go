@@ -0,0 +1,62 @@
+package runtime
+
+import (
+	"testing"
+)
+
+func TestPrintOriginalDeletions(t *testing.T) {
+	source := "x = 1\ny = 2\nz = 3\n"
+	for _, test := range []struct {
+		name     string
+		deleted  []int
+		expected string
+	}{
+		// Deleted nodes take the whitespace before them
+		{"middle", []int{1}, "x = 1\nz = 3\n"},
+		{"last", []int{2}, "x = 1\ny = 2\n"},
+		// The first node takes the whitespace after it, and so does a run of nodes
+		// starting with it
+		{"first", []int{0}, "y = 2\nz = 3\n"},
+		{"first two", []int{0, 1}, "z = 3\n"},
+		{"last two", []int{1, 2}, "x = 1\n"},
+		{"all", []int{0, 1, 2}, "\n"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			root := parsePython(t, source)
+			unparser := NewUnparser([]byte(source), nil)
+			for _, i := range test.deleted {
+				unparser.Delete(root.NamedChild(uint(i)))
+			}
+			if output := string(unparser.Bytes(root)); output != test.expected {
+				t.Errorf("Expected %q, got %q", test.expected, output)
+			}
+		})
+	}
+}
+
+func TestPrintOriginalDeletionNextToInsertion(t *testing.T) {
+	source := "x = 1\ny = 2\n"
+	root := parsePython(t, source)
+	unparser := NewUnparser([]byte(source), nil)
+
+	// A node with an insertion keeps the whitespace before it, as the inserted text
+	// takes its place
+	y := root.NamedChild(1)
+	unparser.Delete(y)
+	unparser.InsertBefore(y, func(p *Printer) { p.Write("w = 0") })
+	if output := string(unparser.Bytes(root)); output != "x = 1\nw = 0\n" {
+		t.Errorf("Expected the insertion to replace y, got %q", output)
+	}
+}
+
+func TestPrintOriginalKeepsCommentGaps(t *testing.T) {
+	source := "f(a,  # first\n  b)\n"
+	root := parsePython(t, source)
+	unparser := NewUnparser([]byte(source), nil)
+
+	// The whitespace between the comment and b is blank, so it goes with b
+	unparser.Delete(findKind(t, root, "argument_list").NamedChild(2))
+	if output := string(unparser.Bytes(root)); output != "f(a,  # first)\n" {
+		t.Errorf("Unexpected output %q", output)
+	}
+}
@@ -0,0 +1,156 @@
+package gent
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// GenerateJSONSchema generates a JSON Schema describing the JSON produced by the
+// `ToJSONNode` and `MarshalJSON` methods of the code generated from the same
+// node-types.json data.
+func (g *Generator) GenerateJSONSchema(data []byte) (string, error) {
+	options := g.options
+	options.Format = FormatJSONSchema
+	return NewGenerator(options).Generate(data)
+}
+
+// jsonSchemaBackend renders the node type model as a JSON Schema.
+type jsonSchemaBackend struct{}
+
+func (b *jsonSchemaBackend) render(nodeTypes nodeTypes, nm *nodeMap) (string, error) {
+	pointSchema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"row":    map[string]any{"type": "integer"},
+			"column": map[string]any{"type": "integer"},
+		},
+		"required": []string{"row", "column"},
+	}
+	rangeSchema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"start_byte":  map[string]any{"type": "integer"},
+			"end_byte":    map[string]any{"type": "integer"},
+			"start_point": schemaRef(pointTypeName),
+			"end_point":   schemaRef(pointTypeName),
+		},
+		"required": []string{"start_byte", "end_byte", "start_point", "end_point"},
+	}
+	defs := map[string]any{
+		pointTypeName: pointSchema,
+		rangeTypeName: rangeSchema,
+	}
+
+	concreteRefs := []any{}
+	for _, nodeType := range nodeTypes {
+		if nodeType.Subtypes != nil {
+			continue
+		}
+		structName, _ := nm.getStructName(nodeType.Type, nodeType.Named)
+		schema, err := nodeSchema(nodeType, nm)
+		if err != nil {
+			return "", fmt.Errorf("Failed to create schema for %s: %w", nodeType.Type, err)
+		}
+		defs[structName] = schema
+		concreteRefs = append(concreteRefs, schemaRef(structName))
+	}
+
+	for _, supertype := range nm.supertypes.FromOldest() {
+		schema, err := typesSchema(supertype.members, nm)
+		if err != nil {
+			return "", fmt.Errorf("Failed to create schema for %s: %w", supertype.name, err)
+		}
+		defs[supertype.name] = schema
+	}
+
+	// Unknown types are never declared, so all we know is their kind
+	for tsKind, structName := range nm.unknown.FromOldest() {
+		schema, err := nodeSchema(nodeType{Type: tsKind}, nm)
+		if err != nil {
+			return "", fmt.Errorf("Failed to create schema for %s: %w", tsKind, err)
+		}
+		defs[structName] = schema
+	}
+
+	schema := map[string]any{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"$defs":   defs,
+	}
+	if nm.root != "" {
+		rootStructName, _ := nm.getStructName(nm.root, true)
+		schema["$ref"] = "#/$defs/" + rootStructName
+	} else {
+		schema["anyOf"] = concreteRefs
+	}
+
+	output, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("Failed to marshal JSON schema: %w", err)
+	}
+	return string(output) + "\n", nil
+}
+
+func schemaRef(structName string) map[string]any {
+	return map[string]any{"$ref": "#/$defs/" + structName}
+}
+
+// nodeSchema creates the schema for a concrete node kind.
+func nodeSchema(nodeType nodeType, nm *nodeMap) (map[string]any, error) {
+	fieldProperties := map[string]any{}
+	requiredFields := []string{}
+	for name, field := range nodeType.Fields.FromOldest() {
+		fieldSchema, err := typesSchema(field.Types, nm)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to create schema for field %s: %w", name, err)
+		}
+		if field.Multiple {
+			fieldSchema = map[string]any{"type": "array", "items": fieldSchema}
+		}
+		fieldProperties[name] = fieldSchema
+		if field.Required {
+			requiredFields = append(requiredFields, name)
+		}
+	}
+
+	properties := map[string]any{
+		"kind":  map[string]any{"const": nodeType.Type},
+		"range": schemaRef(rangeTypeName),
+		"text":  map[string]any{"type": "string"},
+		"fields": map[string]any{
+			"type":                 "object",
+			"properties":           fieldProperties,
+			"required":             requiredFields,
+			"additionalProperties": false,
+		},
+	}
+
+	if len(nodeType.Children.Types) > 0 {
+		childSchema, err := typesSchema(nodeType.Children.Types, nm)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to create schema for children: %w", err)
+		}
+		properties["children"] = map[string]any{"type": "array", "items": childSchema}
+	}
+
+	return map[string]any{
+		"type":       "object",
+		"properties": properties,
+		"required":   []string{"kind", "range"},
+	}, nil
+}
+
+// typesSchema creates the schema for a value that can be any of the given types.
+func typesSchema(types []nodeChildType, nm *nodeMap) (map[string]any, error) {
+	refs := []any{}
+	for _, type_ := range types {
+		structName, ok := nm.getStructName(type_.Type, type_.Named)
+		if !ok {
+			return nil, fmt.Errorf("Failed to find struct name for %s", type_.Type)
+		}
+		refs = append(refs, schemaRef(structName))
+	}
+	if len(refs) == 1 {
+		return refs[0].(map[string]any), nil
+	}
+	return map[string]any{"anyOf": refs}, nil
+}
@@ -0,0 +1,109 @@
+package gent
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/dave/jennifer/jen"
+)
+
+// writeScopeFunctions adds the `ScopeRoles` variable from the configured roles, and the
+// `BuildScopes` function that builds a scope tree with them. The roles are checked
+// against the node types, so that typos fail when generating rather than silently
+// building the wrong scopes.
+func writeScopeFunctions(file *jen.File, nodeTypes nodeTypes, roles Roles, nm *nodeMap) error {
+	kinds := map[string]nodeType{}
+	for _, nodeType := range nodeTypes {
+		if nodeType.Named && nodeType.Subtypes == nil {
+			kinds[nodeType.Type] = nodeType
+		}
+	}
+	checkKinds := func(role string, roleKinds []string) error {
+		for _, kind := range roleKinds {
+			if _, ok := kinds[kind]; !ok {
+				return fmt.Errorf("Unknown %s kind %s, expected a concrete named kind", role, kind)
+			}
+		}
+		return nil
+	}
+	checkFields := func(role string, fields map[string]string) error {
+		for _, kind := range slices.Sorted(maps.Keys(fields)) {
+			if err := checkKinds(role, []string{kind}); err != nil {
+				return err
+			}
+			field := fields[kind]
+			if field == "" {
+				if len(kinds[kind].Children.Types) == 0 {
+					return fmt.Errorf("Kind %s has no children outside of fields for its %s role", kind, role)
+				}
+				continue
+			}
+			nodeType := kinds[kind]
+			if _, ok := nodeType.Fields.Get(field); !ok {
+				return fmt.Errorf("Kind %s has no field %s for its %s role", kind, field, role)
+			}
+		}
+		return nil
+	}
+
+	for _, err := range []error{
+		checkKinds("scope", roles.Scopes),
+		checkFields("definition", roles.Definitions),
+		checkKinds("reference", roles.References),
+		checkKinds("pattern", roles.Patterns),
+		checkFields("ignored", roles.Ignored),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+
+	kindList := func(kinds []string) jen.Code {
+		values := []jen.Code{}
+		for _, kind := range kinds {
+			values = append(values, jen.Lit(kind))
+		}
+		return jen.Index().Qual(runtimePackage, "SyntaxKind").Values(values...)
+	}
+	fieldMap := func(fields map[string]string) jen.Code {
+		dict := jen.Dict{}
+		for kind, field := range fields {
+			dict[jen.Lit(kind)] = jen.Lit(field)
+		}
+		return jen.Map(jen.Qual(runtimePackage, "SyntaxKind")).String().Values(dict)
+	}
+
+	scopeRolesName := nm.names.get("ScopeRoles")
+	buildScopesName := nm.names.get("BuildScopes")
+	writeDocComment(file, scopeRolesName+" are the roles of kinds from the gent config, used by "+buildScopesName+".")
+	file.Var().Id(scopeRolesName).Op("=").Op("&").Qual(runtimePackage, "ScopeRoles").Values(jen.Dict{
+		jen.Id("Scopes"):      kindList(roles.Scopes),
+		jen.Id("Definitions"): fieldMap(roles.Definitions),
+		jen.Id("References"):  kindList(roles.References),
+		jen.Id("Patterns"):    kindList(roles.Patterns),
+		jen.Id("Ignored"):     fieldMap(roles.Ignored),
+	})
+
+	tsNode := jen.Op("*").Qual("github.com/tree-sitter/go-tree-sitter", "Node")
+	writeDocComment(
+		file,
+		buildScopesName+" builds the scope tree of the tree rooted at the given node using "+scopeRolesName+", declaring the names of definitions in their scopes and resolving references to them.",
+		"The scopes, declarations and references hold typed nodes, e.g. a `*FunctionDefinition` for a function's scope.",
+	)
+	file.Func().Id(buildScopesName).
+		Params(jen.Id("root").Add(tsNode), jen.Id("source").Index().Byte()).
+		Op("*").Qual(runtimePackage, "Scopes").
+		Block(
+			jen.Return(jen.Qual(runtimePackage, "BuildScopes").Call(
+				jen.Id(scopeRolesName),
+				jen.Id("root"),
+				jen.Id("source"),
+				jen.Func().Params(jen.Id("node").Add(tsNode)).Params(jen.Qual(runtimePackage, "TypedNode"), jen.Error()).Block(
+					jen.Return(jen.Id(nm.names.get("Wrap")).Call(jen.Id("node"))),
+				),
+			)),
+		)
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package gent
+
+import (
+	"github.com/dave/jennifer/jen"
+)
+
+// writeSelectorFunctions adds the `CompileSelector` and `MustCompileSelector`
+// functions, which compile selectors against `Grammar`, and the `Select` function,
+// which returns the typed nodes matching one.
+func writeSelectorFunctions(file *jen.File, nm *nodeMap) {
+	typedNodeName := nm.names.get("TypedNode")
+	tsNode := jen.Op("*").Qual("github.com/tree-sitter/go-tree-sitter", "Node")
+	selectorType := jen.Op("*").Qual(runtimePackage, "Selector")
+	compileSelectorName := nm.names.get("CompileSelector")
+	mustCompileSelectorName := nm.names.get("MustCompileSelector")
+	selectName := nm.names.get("Select")
+	grammarName := nm.names.get("Grammar")
+
+	writeDocComment(
+		file,
+		compileSelectorName+" compiles a selector such as `class_definition > block function_definition`, checking its kinds and fields against "+grammarName+". Unknown kinds and fields are reported with suggestions. See `runtime.Selector` for the syntax.",
+	)
+	file.Func().Id(compileSelectorName).
+		Params(jen.Id("selector").String()).
+		Params(selectorType.Clone(), jen.Error()).
+		Block(
+			jen.Return(jen.Qual(runtimePackage, "CompileSelector").Call(jen.Id(grammarName), jen.Id("selector"))),
+		)
+
+	writeDocComment(
+		file,
+		mustCompileSelectorName+" is like "+compileSelectorName+", but panics if the selector is invalid. It's intended for selectors in package-level variables, so that they're checked when the package is initialised.",
+	)
+	file.Func().Id(mustCompileSelectorName).
+		Params(jen.Id("selector").String()).
+		Add(selectorType.Clone()).
+		Block(
+			jen.List(jen.Id("compiled"), jen.Err()).Op(":=").Id(compileSelectorName).Call(jen.Id("selector")),
+			jen.If(jen.Err().Op("!=").Nil()).Block(
+				jen.Panic(jen.Err()),
+			),
+			jen.Return(jen.Id("compiled")),
+		)
+
+	writeDocComment(
+		file,
+		selectName+" returns the typed nodes in the tree rooted at the given node that match the selector, in depth-first order.",
+	)
+	file.Func().Id(selectName).
+		Params(
+			jen.Id("root").Add(tsNode),
+			jen.Id("source").Index().Byte(),
+			jen.Id("selector").Add(selectorType.Clone()),
+		).
+		Params(jen.Index().Id(typedNodeName), jen.Error()).
+		Block(
+			jen.Id("nodes").Op(":=").Index().Id(typedNodeName).Values(),
+			jen.For(
+				jen.List(jen.Id("_"), jen.Id("node")).Op(":=").Range().Id("selector").Dot("Select").Call(jen.Id("root"), jen.Id("source")),
+			).Block(
+				jen.List(jen.Id("typed"), jen.Err()).Op(":=").Id(nm.names.get("Wrap")).Call(jen.Op("&").Id("node")),
+				jen.If(jen.Err().Op("!=").Nil()).Block(
+					jen.Return(jen.Nil(), jen.Err()),
+				),
+				jen.Id("nodes").Op("=").Append(jen.Id("nodes"), jen.Id("typed")),
+			),
+			jen.Return(jen.Id("nodes"), jen.Nil()),
+		)
+}
@@ -1,5 +1,6 @@
 import sys
 
+# Entry point
 def main():
     print("Hello, world!")
     return 0
@@ -0,0 +1,88 @@
+// Package tool implements the gent commands that parse sources, such as `gent corpus`.
+// They use the generated package registered with `runtime.RegisterBinding`, which the
+// gent command links in by running a small program that imports it, so that gent
+// itself doesn't need to contain any grammars.
+package tool
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/isaacharrisholt/gent/runtime"
+)
+
+// Corpus checks the examples in Tree-sitter corpus files against the bound package.
+// Directories are expanded to the `.txt` files they contain.
+//
+// The expected tree of each example is checked against the grammar, and its source is
+// parsed and checked with the generated types, calling every accessor. Examples with
+// the `skip` attribute are ignored. The problems are written to w, and an error is
+// returned if there are any.
+func Corpus(w io.Writer, paths []string) error {
+	binding, err := runtime.LookupBinding()
+	if err != nil {
+		return err
+	}
+
+	corpusPaths := []string{}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("Failed to read from %s: %w", path, err)
+		}
+		if !info.IsDir() {
+			corpusPaths = append(corpusPaths, path)
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(path, "*.txt"))
+		if err != nil {
+			return fmt.Errorf("Failed to list corpus files in %s: %w", path, err)
+		}
+		corpusPaths = append(corpusPaths, matches...)
+	}
+
+	problemCount := 0
+	for _, path := range corpusPaths {
+		corpus, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("Failed to read from %s: %w", path, err)
+		}
+		examples, err := runtime.ParseCorpus(corpus)
+		if err != nil {
+			return fmt.Errorf("Failed to parse corpus file %s: %w", path, err)
+		}
+
+		for _, example := range examples {
+			if example.HasAttribute("skip") {
+				continue
+			}
+			sexp, err := runtime.ParseSExpression(example.Expected)
+			if err != nil {
+				return fmt.Errorf("Failed to parse expected tree of %q in %s: %w", example.Name, path, err)
+			}
+			for _, problem := range runtime.CheckSExpression(binding.Grammar, sexp) {
+				fmt.Fprintf(w, "%s: %s: expected tree: %s\n", path, example.Name, problem)
+				problemCount++
+			}
+		}
+
+		results, err := runtime.RunCorpus(binding.Language, examples, binding.CheckTree)
+		if err != nil {
+			return fmt.Errorf("Failed to parse the examples in %s: %w", path, err)
+		}
+		for _, result := range results {
+			for _, problem := range result.Problems {
+				fmt.Fprintf(w, "%s: %s: %s\n", path, result.Example.Name, problem)
+				problemCount++
+			}
+		}
+	}
+
+	if problemCount > 0 {
+		return fmt.Errorf("Found %d problems in %d corpus files", problemCount, len(corpusPaths))
+	}
+	fmt.Fprintf(w, "No problems found in %d corpus files\n", len(corpusPaths))
+	return nil
+}
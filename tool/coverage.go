@@ -0,0 +1,56 @@
+package tool
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/isaacharrisholt/gent/runtime"
+)
+
+// Coverage parses the given source files with the bound package, and writes a report
+// of which kinds, fields and union members declared in its grammar occur in them.
+//
+// Directories are walked, parsing the files with one of the given extensions, e.g.
+// `.py`, or every file if there are none. Files given explicitly are always parsed.
+func Coverage(w io.Writer, roots []string, extensions []string) error {
+	binding, err := runtime.LookupBinding()
+	if err != nil {
+		return err
+	}
+	parser, err := binding.NewParser()
+	if err != nil {
+		return err
+	}
+	defer parser.Close()
+
+	coverage := runtime.NewCoverage(binding.Grammar)
+	for _, root := range roots {
+		err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if entry.IsDir() || (path != root && len(extensions) > 0 && !slices.Contains(extensions, filepath.Ext(path))) {
+				return nil
+			}
+
+			source, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("Failed to read from %s: %w", path, err)
+			}
+			tree := parser.Parse(source, nil)
+			defer tree.Close()
+			coverage.AddTree(tree.RootNode())
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("Failed to parse sources in %s: %w", root, err)
+		}
+	}
+
+	_, err = io.WriteString(w, coverage.Report().String())
+	return err
+}
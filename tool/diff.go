@@ -0,0 +1,40 @@
+package tool
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/isaacharrisholt/gent/runtime"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// Diff parses two source files with the bound package, and writes the structural
+// differences between them, with children aligned by the fields declared in its
+// grammar.
+func Diff(w io.Writer, oldPath string, newPath string) error {
+	binding, err := runtime.LookupBinding()
+	if err != nil {
+		return err
+	}
+	parser, err := binding.NewParser()
+	if err != nil {
+		return err
+	}
+	defer parser.Close()
+
+	sources := [2][]byte{}
+	trees := [2]*tree_sitter.Tree{}
+	for i, path := range []string{oldPath, newPath} {
+		sources[i], err = os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("Failed to read from %s: %w", path, err)
+		}
+		trees[i] = parser.Parse(sources[i], nil)
+		defer trees[i].Close()
+	}
+
+	diff := runtime.DiffTrees(binding.Grammar, trees[0].RootNode(), trees[1].RootNode(), sources[0], sources[1], binding.Wrap)
+	_, err = io.WriteString(w, diff.String())
+	return err
+}
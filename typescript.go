@@ -0,0 +1,95 @@
+package gent
+
+import (
+	"fmt"
+	"strings"
+)
+
+// typeScriptBackend renders the node type model as TypeScript type definitions
+// describing the JSON output of the generated Go types.
+type typeScriptBackend struct{}
+
+func (b *typeScriptBackend) render(nodeTypes nodeTypes, nm *nodeMap) (string, error) {
+	output := &strings.Builder{}
+	fmt.Fprintf(output, "export interface %s {\n  row: number;\n  column: number;\n}\n\n", pointTypeName)
+	fmt.Fprintf(output, "export interface %s {\n  start_byte: number;\n  end_byte: number;\n  start_point: %s;\n  end_point: %s;\n}\n", rangeTypeName, pointTypeName, pointTypeName)
+
+	for _, nodeType := range nodeTypes {
+		if nodeType.Subtypes != nil {
+			continue
+		}
+		structName, _ := nm.getStructName(nodeType.Type, nodeType.Named)
+		if err := writeTypeScriptInterface(output, structName, nodeType, nm); err != nil {
+			return "", fmt.Errorf("Failed to write interface for %s: %w", nodeType.Type, err)
+		}
+	}
+
+	for _, supertype := range nm.supertypes.FromOldest() {
+		members, err := typeScriptUnion(supertype.members, nm)
+		if err != nil {
+			return "", fmt.Errorf("Failed to write type for %s: %w", supertype.name, err)
+		}
+		fmt.Fprintf(output, "\nexport type %s = %s;\n", supertype.name, members)
+	}
+
+	for tsKind, structName := range nm.unknown.FromOldest() {
+		if err := writeTypeScriptInterface(output, structName, nodeType{Type: tsKind}, nm); err != nil {
+			return "", fmt.Errorf("Failed to write interface for %s: %w", tsKind, err)
+		}
+	}
+
+	return output.String(), nil
+}
+
+func writeTypeScriptInterface(output *strings.Builder, structName string, nodeType nodeType, nm *nodeMap) error {
+	fmt.Fprintf(output, "\nexport interface %s {\n  kind: %q;\n  range: %s;\n  text?: string;\n", structName, nodeType.Type, rangeTypeName)
+
+	output.WriteString("  fields?: {\n")
+	for name, field := range nodeType.Fields.FromOldest() {
+		members, err := typeScriptUnion(field.Types, nm)
+		if err != nil {
+			return fmt.Errorf("Failed to write field %s: %w", name, err)
+		}
+		optional := "?"
+		if field.Required {
+			optional = ""
+		}
+		if field.Multiple {
+			members = typeScriptArray(members, len(field.Types))
+		}
+		fmt.Fprintf(output, "    %s%s: %s;\n", name, optional, members)
+	}
+	output.WriteString("  };\n")
+
+	if len(nodeType.Children.Types) > 0 {
+		members, err := typeScriptUnion(nodeType.Children.Types, nm)
+		if err != nil {
+			return fmt.Errorf("Failed to write children: %w", err)
+		}
+		fmt.Fprintf(output, "  children?: %s;\n", typeScriptArray(members, len(nodeType.Children.Types)))
+	}
+
+	output.WriteString("}\n")
+	return nil
+}
+
+// typeScriptUnion returns a union of the interfaces for the given types. Unlike the Go
+// output, unions that aren't supertypes are written inline rather than named.
+func typeScriptUnion(types []nodeChildType, nm *nodeMap) (string, error) {
+	members := []string{}
+	for _, type_ := range types {
+		structName, ok := nm.getStructName(type_.Type, type_.Named)
+		if !ok {
+			return "", fmt.Errorf("Failed to find struct name for %s", type_.Type)
+		}
+		members = append(members, structName)
+	}
+	return strings.Join(members, " | "), nil
+}
+
+func typeScriptArray(members string, count int) string {
+	if count > 1 {
+		return "(" + members + ")[]"
+	}
+	return members + "[]"
+}
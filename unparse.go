@@ -0,0 +1,33 @@
+package gent
+
+import (
+	"github.com/dave/jennifer/jen"
+)
+
+// writeUnparserFunction adds the `NewUnparser` function, which creates a
+// `runtime.Unparser` whose per-kind formatting rules are a visitor's `Visit<Struct>`
+// methods, dispatched like they are by `Walk`.
+func writeUnparserFunction(file *jen.File, nm *nodeMap) {
+	newUnparserName := nm.names.get("NewUnparser")
+	tsNode := jen.Op("*").Qual("github.com/tree-sitter/go-tree-sitter", "Node")
+
+	writeDocComment(
+		file,
+		newUnparserName+" creates an unparser that re-emits the source of a tree after changes, keeping the original text of nodes that aren't changed.",
+		"Formatting rules are supplied by a visitor, as passed to `"+nm.names.get("Walk")+"`, which can be nil. Its `Visit<Struct>` methods are called for each named node that isn't replaced. They return true to keep the node's original text, applying the rules to its children, or false once they've emitted the node themselves. To emit text, the visitor embeds `runtime.Formatter`, e.g. `func (f *formatter) VisitCall(call *Call) bool`, which uses `f.Write` and `f.Print`.",
+	)
+	file.Func().Id(newUnparserName).
+		Params(jen.Id("source").Index().Byte(), jen.Id("visitor").Any()).
+		Op("*").Qual(runtimePackage, "Unparser").
+		Block(
+			jen.Return(jen.Qual(runtimePackage, "NewUnparser").Call(
+				jen.Id("source"),
+				jen.Qual(runtimePackage, "VisitorRule").Call(
+					jen.Id("visitor"),
+					jen.Func().Params(jen.Id("node").Add(tsNode)).Bool().Block(
+						jen.Return(jen.Id("visit").Call(jen.Id("visitor"), jen.Id("node"), jen.Nil())),
+					),
+				),
+			)),
+		)
+}
@@ -0,0 +1,130 @@
+package gent
+
+import (
+	"github.com/dave/jennifer/jen"
+)
+
+// visitMethodName returns the name of the visitor method for the given struct.
+func visitMethodName(structName string) string {
+	return "Visit" + structName
+}
+
+// writeVisitorFunctions adds the `Walk` function, which calls a visitor's
+// `Visit<Struct>` methods for each node in a tree, and the functions used to measure
+// which of those methods are reached.
+func writeVisitorFunctions(file *jen.File, nodeTypes nodeTypes, nm *nodeMap) {
+	tsNode := jen.Op("*").Qual("github.com/tree-sitter/go-tree-sitter", "Node")
+
+	// Each kind is dispatched to the visitor if it has a method for it
+	visitorInterface := func(structName string) *jen.Statement {
+		return jen.Interface(
+			jen.Id(visitMethodName(structName)).Params(jen.Op("*").Id(structName)).Bool(),
+		)
+	}
+
+	cases := []jen.Code{}
+	handledChecks := []jen.Code{}
+	for _, nodeType := range nodeTypes {
+		if nodeType.Subtypes != nil || !nodeType.Named {
+			continue
+		}
+		structName, _ := nm.getStructName(nodeType.Type, nodeType.Named)
+		cases = append(cases, jen.Case(jen.Id(syntaxKindConstName(structName))).Block(
+			jen.If(
+				jen.List(jen.Id("v"), jen.Id("ok")).Op(":=").Id("visitor").Assert(visitorInterface(structName)),
+				jen.Id("ok"),
+			).Block(
+				jen.If(jen.Id("coverage").Op("!=").Nil()).Block(
+					jen.Id("coverage").Dot("Reach").Call(jen.Id(syntaxKindConstName(structName))),
+				),
+				jen.Return(jen.Id("v").Dot(visitMethodName(structName)).Call(
+					jen.Op("&").Id(structName).Values(jen.Dict{jen.Id("Node"): jen.Op("*").Id("node")}),
+				)),
+			),
+		))
+		handledChecks = append(handledChecks, jen.If(
+			jen.List(jen.Id("_"), jen.Id("ok")).Op(":=").Id("visitor").Assert(visitorInterface(structName)),
+			jen.Id("ok"),
+		).Block(
+			jen.Id("kinds").Op("=").Append(jen.Id("kinds"), jen.Id(syntaxKindConstName(structName))),
+		))
+	}
+
+	walkName := nm.names.get("Walk")
+	walkCoverageName := nm.names.get("WalkCoverage")
+	newCoverageName := nm.names.get("NewCoverage")
+	visitorKindsName := nm.names.get("VisitorKinds")
+	grammarName := nm.names.get("Grammar")
+
+	writeDocComment(
+		file,
+		walkName+" traverses the tree rooted at the given node in depth-first order. For each named node, it calls the visitor's `Visit<Struct>` method for the node's kind, e.g. `VisitModule(*Module) bool`, if the visitor has one. If the method returns false, the node's children are skipped.",
+		"Visitors only need to implement the methods for the kinds they handle.",
+	)
+	file.Func().Id(walkName).Params(jen.Id("visitor").Any(), jen.Id("root").Add(tsNode)).Block(
+		jen.Id("walk").Call(jen.Id("visitor"), jen.Id("root"), jen.Nil()),
+	)
+
+	writeDocComment(
+		file,
+		walkCoverageName+" walks the tree like `"+walkName+"`, recording in the coverage which kinds, fields and field members occur in the tree, and which of the visitor's methods are reached.",
+	)
+	file.Func().Id(walkCoverageName).Params(
+		jen.Id("visitor").Any(),
+		jen.Id("root").Add(tsNode),
+		jen.Id("coverage").Op("*").Qual(runtimePackage, "Coverage"),
+	).Block(
+		jen.Id("coverage").Dot("AddTree").Call(jen.Id("root")),
+		jen.Id("coverage").Dot("Handle").Call(jen.Id(visitorKindsName).Call(jen.Id("visitor")).Op("...")),
+		jen.Id("walk").Call(jen.Id("visitor"), jen.Id("root"), jen.Id("coverage")),
+	)
+
+	writeDocComment(file, newCoverageName+" creates an empty coverage for the kinds in `"+grammarName+"`.")
+	file.Func().Id(newCoverageName).Params().Op("*").Qual(runtimePackage, "Coverage").Block(
+		jen.Return(jen.Qual(runtimePackage, "NewCoverage").Call(jen.Id(grammarName))),
+	)
+
+	file.Comment("visit calls the visitor's method for the node's kind, if it has one, returning")
+	file.Comment("whether to descend into the node's children.")
+	file.Func().Id("visit").Params(
+		jen.Id("visitor").Any(),
+		jen.Id("node").Add(tsNode),
+		jen.Id("coverage").Op("*").Qual(runtimePackage, "Coverage"),
+	).Bool().Block(
+		// Unnamed kinds can share names with named ones, but are never visited
+		jen.If(jen.Op("!").Id("node").Dot("IsNamed").Call()).Block(jen.Return(jen.True())),
+		jen.Switch(jen.Id("node").Dot("Kind").Call()).Block(cases...),
+		jen.Return(jen.True()),
+	)
+
+	file.Func().Id("walk").Params(
+		jen.Id("visitor").Any(),
+		jen.Id("node").Add(tsNode),
+		jen.Id("coverage").Op("*").Qual(runtimePackage, "Coverage"),
+	).Block(
+		jen.If(jen.Op("!").Id("visit").Call(jen.Id("visitor"), jen.Id("node"), jen.Id("coverage"))).Block(jen.Return()),
+		jen.For(
+			jen.Id("index").Op(":=").Uint().Parens(jen.Lit(0)),
+			jen.Id("index").Op("<").Id("node").Dot("ChildCount").Call(),
+			jen.Id("index").Op("++"),
+		).Block(
+			jen.Id("walk").Call(
+				jen.Id("visitor"),
+				jen.Id("node").Dot("Child").Call(jen.Id("index")),
+				jen.Id("coverage"),
+			),
+		),
+	)
+
+	writeDocComment(file, visitorKindsName+" returns the kinds the visitor has `Visit<Struct>` methods for.")
+	syntaxKindName := nm.names.get("SyntaxKind")
+	file.Func().Id(visitorKindsName).Params(jen.Id("visitor").Any()).Index().Id(syntaxKindName).Block(
+		append(
+			append(
+				[]jen.Code{jen.Id("kinds").Op(":=").Index().Id(syntaxKindName).Values()},
+				handledChecks...,
+			),
+			jen.Return(jen.Id("kinds")),
+		)...,
+	)
+}